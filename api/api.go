@@ -1,13 +1,120 @@
 package api
 
 import (
+	"errors"
+	"os"
+	"strings"
+
 	"github.com/go-resty/resty/v2"
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/netutils"
 )
 
-var client = resty.New()
+var client = resty.NewWithClient(netutils.Client)
+var config util.Config
+
+// Configure applies the user settings to the api, main calls it before any command runs
+func Configure(c util.Config) {
+	config = c
+	netutils.Configure(c)
+
+	setEndpoint(&MODRINTH_API_BASE, "MODRINTH_API_BASE", c.ModrinthApiBase)
+	setEndpoint(&CURSE_API_BASE, "CURSE_API_BASE", c.CurseApiBase)
+	setEndpoint(&FABRIC_META_BASE, "FABRIC_META_BASE", c.FabricMetaBase)
+	setEndpoint(&QUILT_META_BASE, "QUILT_META_BASE", c.QuiltMetaBase)
+	setEndpoint(&MOJANG_MANIFEST_URL, "MOJANG_MANIFEST_URL", c.MojangManifestUrl)
+	setEndpoint(&MOJANG_LIBRARIES_BASE, "MOJANG_LIBRARIES_BASE", c.MojangLibrariesBase)
+	setEndpoint(&MOJANG_RESOURCES_BASE, "MOJANG_RESOURCES_BASE", c.MojangResourcesBase)
+	setEndpoint(&GITHUB_API_BASE, "GITHUB_API_BASE", c.GithubApiBase)
+	netutils.SetUncachedBases(MOJANG_LIBRARIES_BASE, MOJANG_RESOURCES_BASE)
+	configureGithub()
+	configureMaven()
+}
+
+// setEndpoint overrides an upstream url, the MODMAN_ prefixed environment variable wins over the config
+func setEndpoint(endpoint *string, name string, value string) {
+	if env := os.Getenv("MODMAN_" + name); env != "" {
+		value = env
+	}
+	if value != "" {
+		*endpoint = strings.TrimSuffix(value, "/")
+	}
+}
+
+type SearchHit struct {
+	Source        string
+	Id            string
+	Slug          string
+	Name          string
+	Description   string
+	Authors       []string
+	Categories    []string
+	Downloads     int
+	LatestVersion string
+}
+
+type ModInfo struct {
+	Source      string
+	Id          string
+	Slug        string
+	Name        string
+	Description string
+	License     string
+	Links       map[string]string
+	ClientSide  string
+	ServerSide  string
+	Versions    []string
+}
 
 type Version struct {
 	Version string
 	Stable  bool
 	Url     string
 }
+
+// GetLoaderGameVersions returns the game versions a loader supports
+func GetLoaderGameVersions(loader string) (v []string, e error) {
+	switch loader {
+	case "fabric":
+		return GetFabricGameVersions(), nil
+	case "quilt":
+		return GetQuiltGameVersions(), nil
+	}
+	return nil, errors.New("unknown loader")
+}
+
+// GetLoaderVersions returns the versions of a loader, newest first
+func GetLoaderVersions(loader string) (v []Version, e error) {
+	switch loader {
+	case "fabric":
+		return GetFabricLoaderVersions(), nil
+	case "quilt":
+		return GetQuiltLoaderVersions(), nil
+	}
+	return nil, errors.New("unknown loader")
+}
+
+// CheckVersion tells apart versions that do not exist from versions the loader does not support yet, vanilla instances have no loader to check
+func CheckVersion(loader string, version string) error {
+	found, err := IsMcVersion(version)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("unknown version")
+	}
+
+	if loader == "" {
+		return nil
+	}
+
+	gameVersions, err1 := GetLoaderGameVersions(loader)
+	if err1 != nil {
+		return err1
+	}
+
+	if !util.Contains(gameVersions, version) {
+		return errors.New("version not supported")
+	}
+	return nil
+}
@@ -3,101 +3,401 @@ package api
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
 	"strconv"
 	"time"
 
+	"github.com/go-resty/resty/v2"
 	"github.com/mrnavastar/modman/util"
 )
 
+const curseGameId = "432"
+const curseModsClassId = "6"
+const curseRequiredDependency = 3
+
+// curseClassIds are the curseforge classes of the pack types, mods first so they win when a slug is in several classes
+var curseClassIds = []struct {
+	PackType string
+	ClassId  string
+	Section  string
+}{
+	{"", curseModsClassId, "mc-mods"},
+	{"resourcepack", "12", "texture-packs"},
+	{"shader", "6552", "shaders"},
+	{"datapack", "6945", "data-packs"},
+}
+
 type curseProject struct {
-	Id   int
-	Name string
-	Slug string
+	Id            int
+	ClassId       int
+	Name          string
+	Slug          string
+	Summary       string
+	DownloadCount float64
+	Links         struct {
+		WebsiteUrl string
+		WikiUrl    string
+		IssuesUrl  string
+		SourceUrl  string
+	}
+	Authors []struct {
+		Name string
+	}
+	Categories []struct {
+		Name string
+	}
+	LatestFilesIndexes []struct {
+		GameVersion string
+		Filename    string
+		ModLoader   int
+	}
 }
 
 type file struct {
-	Id                      int
-	GameVersionDateReleased string
-	DownloadUrl             string
-	FileName                string
-	GameVersion             []string
-	Modules                 []struct {
-		Foldername string
-	}
+	Id           int
+	ModId        int
+	DisplayName  string
+	FileName     string
+	FileDate     string
+	DownloadUrl  string
+	GameVersions []string
 	Dependencies []struct {
-		AddonId int
+		ModId        int
+		RelationType int
 	}
 }
 
-var CURSE_API_BASE = "https://addons-ecs.forgesvc.net/api/v2"
+var CURSE_API_BASE = "https://api.curseforge.com/v1"
 
-func GetCurseModData(slug string, version string) (m util.ModData, e error) {
-	var project curseProject
-	if _, err := strconv.Atoi(slug); err != nil {
-		var curseProjects []curseProject
-		_, err1 := client.R().SetResult(&curseProjects).SetHeader("content-type", "application/json").Get(CURSE_API_BASE + "/addon/search?gameId=432&searchfilter=" + slug)
-		util.Fatal(err1)
+// getCurseApiKey returns the curseforge api key, the CURSEFORGE_API_KEY environment variable wins over the config
+func getCurseApiKey() string {
+	if key := os.Getenv("CURSEFORGE_API_KEY"); key != "" {
+		return key
+	}
+	return config.CurseApiKey
+}
 
-		for _, p := range curseProjects {
-			if p.Slug == slug {
-				project = p
-			}
+// curseRequest creates a request authenticated with the curseforge api key
+func curseRequest() *resty.Request {
+	return client.R().SetHeader("x-api-key", getCurseApiKey()).SetHeader("Accept", "application/json")
+}
+
+// checkCurseResponse turns the status codes of the curseforge api into errors
+func checkCurseResponse(resp *resty.Response) error {
+	switch resp.StatusCode() {
+	case 200:
+		return nil
+	case 401, 403:
+		if getCurseApiKey() == "" {
+			return errors.New("curseforge api key missing")
 		}
-	} else {
-		_, err1 := client.R().SetResult(&project).Get(CURSE_API_BASE + "/addon/" + slug)
-		util.Fatal(err1)
+		return errors.New("curseforge api key rejected")
+	case 404:
+		return errors.New("invalid slug")
 	}
+	return errors.New("curseforge returned " + resp.Status())
+}
 
-	var files []file
-	resp, err1 := client.R().SetResult(&files).Get(CURSE_API_BASE + "/addon/" + fmt.Sprint(project.Id) + "/files")
-	util.Fatal(err1)
+// getCurseLoaderType returns the modLoaderType curseforge uses for a loader
+func getCurseLoaderType(loader string) string {
+	switch loader {
+	case "fabric":
+		return "4"
+	case "quilt":
+		return "5"
+	}
+	return ""
+}
 
-	if resp.StatusCode() != 200 {
-		return util.ModData{}, errors.New("invalid slug")
+// getCursePackType returns the pack type of a curseforge class, empty for mods
+func getCursePackType(classId int) string {
+	for _, class := range curseClassIds {
+		if class.ClassId == fmt.Sprint(classId) {
+			return class.PackType
+		}
 	}
+	return ""
+}
 
-	var file file
-	var date time.Time
-	for _, f := range files {
-		for _, module := range f.Modules {
-			if module.Foldername == "fabric.mod.json" {
-				for _, v := range f.GameVersion {
-					if v == version {
-						t, err2 := time.Parse(time.RFC3339, f.GameVersionDateReleased)
-						util.Fatal(err2)
-
-						if date.Before(t) {
-							file = f
-							date = t
-						}
-					}
-				}
+// getCurseProject looks up a project by its slug or numeric id. Slugs are searched in the class of the pack type,
+// or in every class when it is empty
+func getCurseProject(slug string, packType string) (p curseProject, e error) {
+	if _, err := strconv.Atoi(slug); err == nil {
+		var result struct {
+			Data curseProject
+		}
+
+		resp, err1 := curseRequest().SetResult(&result).Get(CURSE_API_BASE + "/mods/" + slug)
+		util.Fatal(err1)
+		return result.Data, checkCurseResponse(resp)
+	}
+
+	for _, class := range curseClassIds {
+		if packType != "" && class.PackType != packType {
+			continue
+		}
+
+		var result struct {
+			Data []curseProject
+		}
+		resp, err := curseRequest().SetResult(&result).Get(CURSE_API_BASE + "/mods/search?gameId=" + curseGameId + "&classId=" + class.ClassId + "&slug=" + url.QueryEscape(slug))
+		util.Fatal(err)
+
+		if err1 := checkCurseResponse(resp); err1 != nil {
+			return curseProject{}, err1
+		}
+
+		for _, project := range result.Data {
+			if project.Slug == slug {
+				return project, nil
 			}
 		}
 	}
+	return curseProject{}, errors.New("invalid slug")
+}
 
-	if file.DownloadUrl == "" {
-		return util.ModData{}, errors.New("failed to find matching version")
+// getCurseFiles returns the files of a project for a loader and game version
+func getCurseFiles(projectId int, loader string, version string) (f []file, e error) {
+	var result struct {
+		Data []file
 	}
 
+	request := curseRequest().SetResult(&result).SetQueryParam("pageSize", "50")
+	if version != "" {
+		request.SetQueryParam("gameVersion", version)
+	}
+	if loaderType := getCurseLoaderType(loader); loaderType != "" {
+		request.SetQueryParam("modLoaderType", loaderType)
+	}
+
+	resp, err := request.Get(CURSE_API_BASE + "/mods/" + fmt.Sprint(projectId) + "/files")
+	util.Fatal(err)
+	return result.Data, checkCurseResponse(resp)
+}
+
+// getCurseModData turns a curseforge file into mod data, files the author keeps off third party launchers are
+// returned together with a "distribution disabled" error
+func getCurseModData(project curseProject, file file) (m util.ModData, e error) {
 	var modData = util.ModData{
 		Platform:  "curse",
+		Type:      getCursePackType(project.ClassId),
 		ProjectId: fmt.Sprint(project.Id),
 		Id:        fmt.Sprint(file.Id),
 		Name:      project.Name,
 		Slug:      project.Slug,
+		Version:   file.DisplayName,
 		Url:       file.DownloadUrl,
 		Filename:  file.FileName,
 	}
+	modData.ClientSide, modData.ServerSide = getCurseSides(file.GameVersions)
 
 	for _, mod := range file.Dependencies {
+		if mod.RelationType != curseRequiredDependency {
+			continue
+		}
+
 		var dep = util.Dependency{
-			ProjectId: fmt.Sprint(mod.AddonId),
-			Name:      fmt.Sprint(mod.AddonId),
+			ProjectId: fmt.Sprint(mod.ModId),
+			Name:      fmt.Sprint(mod.ModId),
 			Required:  true,
 		}
 
 		modData.Dependencies = append(modData.Dependencies, dep)
 	}
+
+	if file.DownloadUrl == "" {
+		return modData, errors.New("distribution disabled")
+	}
 	return modData, nil
 }
+
+// GetCurseModData finds the newest file of a project for a loader and game version, packs are matched on the game version only
+func GetCurseModData(slug string, loader string, version string, packType string) (m util.ModData, e error) {
+	project, err := getCurseProject(slug, packType)
+	if err != nil {
+		return util.ModData{}, err
+	}
+
+	if getCursePackType(project.ClassId) != "" {
+		loader = ""
+	}
+
+	files, err1 := getCurseFiles(project.Id, loader, version)
+	if err1 != nil {
+		return util.ModData{}, err1
+	}
+
+	var file file
+	var date time.Time
+	for _, f := range files {
+		t, err2 := time.Parse(time.RFC3339, f.FileDate)
+		if err2 != nil {
+			continue
+		}
+
+		if date.Before(t) {
+			file = f
+			date = t
+		}
+	}
+
+	if file.Id == 0 {
+		return util.ModData{}, errors.New("failed to find matching version")
+	}
+	return getCurseModData(project, file)
+}
+
+// GetCurseFileUrl returns the curseforge page a file can be downloaded from by hand
+func GetCurseFileUrl(mod util.ModData) string {
+	section := "mc-mods"
+	for _, class := range curseClassIds {
+		if class.PackType == mod.Type {
+			section = class.Section
+		}
+	}
+	return "https://www.curseforge.com/minecraft/" + section + "/" + mod.Slug + "/files/" + mod.Id
+}
+
+// GetCurseModDataByFingerprint identifies a jar by its curseforge fingerprint
+func GetCurseModDataByFingerprint(fingerprint uint32) (m util.ModData, e error) {
+	var result struct {
+		Data struct {
+			ExactMatches []struct {
+				Id   int
+				File file
+			}
+		}
+	}
+
+	resp, err := curseRequest().SetBody(map[string][]uint32{"fingerprints": {fingerprint}}).SetResult(&result).Post(CURSE_API_BASE + "/fingerprints")
+	util.Fatal(err)
+
+	if err1 := checkCurseResponse(resp); err1 != nil {
+		return util.ModData{}, err1
+	}
+
+	if len(result.Data.ExactMatches) == 0 {
+		return util.ModData{}, errors.New("no matching file")
+	}
+
+	match := result.Data.ExactMatches[0]
+	project, err2 := getCurseProject(fmt.Sprint(match.Id), "")
+	if err2 != nil {
+		return util.ModData{}, err2
+	}
+	return getCurseModData(project, match.File)
+}
+
+// getCurseSides maps the environment tags curseforge mixes into a file's game versions onto modrinth style side values
+func getCurseSides(gameVersions []string) (client string, server string) {
+	isClient := util.Contains(gameVersions, "Client")
+	isServer := util.Contains(gameVersions, "Server")
+
+	if isClient && !isServer {
+		return "required", "unsupported"
+	}
+	if isServer && !isClient {
+		return "unsupported", "required"
+	}
+	if isClient && isServer {
+		return "required", "required"
+	}
+	return "", ""
+}
+
+// SearchCurseMods searches curseforge for mods, only returning ones with a file for the loader and game version when they are set
+func SearchCurseMods(query string, loader string, version string, limit int) (h []SearchHit, e error) {
+	var result struct {
+		Data []curseProject
+	}
+
+	request := curseRequest().SetResult(&result).SetQueryParams(map[string]string{
+		"gameId":       curseGameId,
+		"classId":      curseModsClassId,
+		"searchFilter": query,
+		"sortField":    "2",
+		"sortOrder":    "desc",
+		"pageSize":     strconv.Itoa(limit),
+	})
+	if version != "" {
+		request.SetQueryParam("gameVersion", version)
+	}
+	if loaderType := getCurseLoaderType(loader); loaderType != "" {
+		request.SetQueryParam("modLoaderType", loaderType)
+	}
+
+	resp, err := request.Get(CURSE_API_BASE + "/mods/search")
+	if err != nil {
+		return nil, err
+	}
+
+	if err1 := checkCurseResponse(resp); err1 != nil {
+		return nil, err1
+	}
+
+	var hits []SearchHit
+	for _, project := range result.Data {
+		hit := SearchHit{
+			Source:      "curse",
+			Id:          fmt.Sprint(project.Id),
+			Slug:        project.Slug,
+			Name:        project.Name,
+			Description: project.Summary,
+			Downloads:   int(project.DownloadCount),
+		}
+
+		for _, author := range project.Authors {
+			hit.Authors = append(hit.Authors, author.Name)
+		}
+		for _, category := range project.Categories {
+			hit.Categories = append(hit.Categories, category.Name)
+		}
+		for _, file := range project.LatestFilesIndexes {
+			if (file.GameVersion == version || version == "") && (loader == "" || fmt.Sprint(file.ModLoader) == getCurseLoaderType(loader)) {
+				hit.LatestVersion = file.Filename
+				break
+			}
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+func GetCurseModInfo(slug string, loader string, version string) (i ModInfo, e error) {
+	project, err := getCurseProject(slug, "")
+	if err != nil {
+		return ModInfo{}, err
+	}
+
+	if getCursePackType(project.ClassId) != "" {
+		loader = ""
+	}
+
+	files, err1 := getCurseFiles(project.Id, loader, version)
+	if err1 != nil {
+		return ModInfo{}, err1
+	}
+
+	info := ModInfo{
+		Source:      "curse",
+		Id:          fmt.Sprint(project.Id),
+		Slug:        project.Slug,
+		Name:        project.Name,
+		Description: project.Summary,
+		Links: map[string]string{
+			"Page":   project.Links.WebsiteUrl,
+			"Source": project.Links.SourceUrl,
+			"Issues": project.Links.IssuesUrl,
+			"Wiki":   project.Links.WikiUrl,
+		},
+	}
+
+	for _, f := range files {
+		info.Versions = append(info.Versions, f.FileName)
+		if info.ClientSide == "" && info.ServerSide == "" {
+			info.ClientSide, info.ServerSide = getCurseSides(f.GameVersions)
+		}
+	}
+	return info, nil
+}
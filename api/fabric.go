@@ -10,12 +10,17 @@ import (
 	"github.com/pterm/pterm"
 )
 
-func GetLatestFabricLoaderVersion() (s string, e error) {
+var FABRIC_META_BASE = "https://meta.fabricmc.net/v2"
+
+func GetFabricLoaderVersions() []Version {
 	var loaderVersions []Version
-	_, err := client.R().SetResult(&loaderVersions).Get("https://meta.fabricmc.net/v2/versions/loader")
+	_, err := client.R().SetResult(&loaderVersions).Get(FABRIC_META_BASE + "/versions/loader")
 	util.Fatal(err)
+	return loaderVersions
+}
 
-	for _, loaderVersion := range loaderVersions {
+func GetLatestFabricLoaderVersion() (s string, e error) {
+	for _, loaderVersion := range GetFabricLoaderVersions() {
 		if loaderVersion.Stable {
 			return loaderVersion.Version, nil
 		}
@@ -24,7 +29,7 @@ func GetLatestFabricLoaderVersion() (s string, e error) {
 }
 
 func DownloadFabricJson(state *fileutils.State, gameVersion string, loaderVersion string) {
-	response, err := client.R().Get("https://meta.fabricmc.net/v2/versions/loader/" + gameVersion + "/" + loaderVersion + "/profile/json")
+	response, err := client.R().Get(FABRIC_META_BASE + "/versions/loader/" + gameVersion + "/" + loaderVersion + "/profile/json")
 	util.Fatal(err)
 
 	profileName := "fabric-loader-" + loaderVersion + "-" + gameVersion
@@ -40,17 +45,35 @@ func DownloadFabricJson(state *fileutils.State, gameVersion string, loaderVersio
 	util.Fatal(err2)
 }
 
-func IsFabricVersionSupported(version string) bool {
+// InstallFabricServer downloads the fabric server launcher into dir, it expects the vanilla jar to be named server.jar
+func InstallFabricServer(gameVersion string, loaderVersion string, dir string) error {
+	var installerVersions []Version
+	_, err := client.R().SetResult(&installerVersions).Get(FABRIC_META_BASE + "/versions/installer")
+	util.Fatal(err)
+
+	for _, installerVersion := range installerVersions {
+		if installerVersion.Stable {
+			fileutils.DownloadFile(FABRIC_META_BASE+"/versions/loader/"+gameVersion+"/"+loaderVersion+"/"+installerVersion.Version+"/server/jar", dir+"/fabric-server-launch.jar")
+			return nil
+		}
+	}
+	return errors.New("failed to find a stable installer version")
+}
+
+func GetFabricGameVersions() []string {
 	var versions []Version
-	_, err := client.R().SetResult(&versions).Get("https://meta.fabricmc.net/v2/versions/game")
+	_, err := client.R().SetResult(&versions).Get(FABRIC_META_BASE + "/versions/game")
 	if err != nil {
 		pterm.Fatal.Println(err)
 	}
 
+	var gameVersions []string
 	for _, v := range versions {
-		if v.Version == version {
-			return true
-		}
+		gameVersions = append(gameVersions, v.Version)
 	}
-	return false
+	return gameVersions
+}
+
+func IsFabricVersionSupported(version string) bool {
+	return util.Contains(GetFabricGameVersions(), version)
 }
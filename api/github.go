@@ -0,0 +1,210 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+	"github.com/mrnavastar/modman/util/netutils"
+)
+
+var GITHUB_API_BASE = "https://api.github.com"
+
+type githubRelease struct {
+	Tag_name   string
+	Name       string
+	Draft      bool
+	Prerelease bool
+	Assets     []githubAsset
+}
+
+type githubAsset struct {
+	Id                   int
+	Name                 string
+	Url                  string
+	Browser_download_url string
+}
+
+type githubRepo struct {
+	Name        string
+	Full_name   string
+	Description string
+	Html_url    string
+	Homepage    string
+	Has_issues  bool
+	Has_wiki    bool
+	License     struct {
+		Name string
+	}
+}
+
+// getGithubToken returns the token for private repos, the GITHUB_TOKEN environment variable wins over the config
+func getGithubToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return config.GithubToken
+}
+
+// configureGithub makes asset downloads send the file itself instead of its details, and sends the token with every request
+// to the github api when there is one
+func configureGithub() {
+	netutils.SetUrlHeader("^"+regexp.QuoteMeta(GITHUB_API_BASE)+"/repos/[^/]+/[^/]+/releases/assets/", "Accept", "application/octet-stream")
+
+	token := getGithubToken()
+	if token == "" {
+		return
+	}
+
+	if base, err := url.Parse(GITHUB_API_BASE); err == nil {
+		netutils.SetHostHeader(base.Host, "Authorization", "Bearer "+token)
+	}
+}
+
+func githubRequest() *resty.Request {
+	return client.R().SetHeader("Accept", "application/vnd.github+json")
+}
+
+// checkGithubResponse turns the status codes of the github api into errors
+func checkGithubResponse(resp *resty.Response) error {
+	switch resp.StatusCode() {
+	case 200:
+		return nil
+	case 401:
+		return errors.New("github token rejected")
+	case 404:
+		return errors.New("invalid slug")
+	}
+	return errors.New("github returned " + resp.Status())
+}
+
+// isGithubAssetMatch checks an asset name against the pattern of a mod, without one any jar that is not sources or docs matches
+func isGithubAssetMatch(name string, pattern string) bool {
+	if pattern != "" {
+		match, _ := path.Match(pattern, name)
+		return match
+	}
+
+	if !strings.HasSuffix(name, ".jar") {
+		return false
+	}
+	for _, suffix := range []string{"-sources.jar", "-javadoc.jar", "-dev.jar"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// getGithubAssetModJson downloads an asset to read its fabric.mod.json, github has no metadata about game versions.
+// The download is kept, so installing the asset does not fetch it again
+func getGithubAssetModJson(asset githubAsset) (m fileutils.ModJson, e error) {
+	file, err := fileutils.InspectFile(asset.Url)
+	if err != nil {
+		return fileutils.ModJson{}, err
+	}
+	return fileutils.GetModJsonFromJar(file)
+}
+
+// GetGithubModData finds the newest release asset of owner/repo matching the pattern whose depends.minecraft allows the game version.
+// The release tag is used as the version of the mod
+func GetGithubModData(repo string, version string, pattern string) (m util.ModData, e error) {
+	var releases []githubRelease
+	resp, err := githubRequest().SetResult(&releases).SetQueryParam("per_page", "30").Get(GITHUB_API_BASE + "/repos/" + repo + "/releases")
+	if err != nil {
+		return util.ModData{}, err
+	}
+
+	if err1 := checkGithubResponse(resp); err1 != nil {
+		return util.ModData{}, err1
+	}
+
+	for _, release := range releases {
+		if release.Draft {
+			continue
+		}
+
+		for _, asset := range release.Assets {
+			if !isGithubAssetMatch(asset.Name, pattern) {
+				continue
+			}
+
+			modJson, err2 := getGithubAssetModJson(asset)
+			if err2 != nil || !util.MatchesVersionPredicate(modJson.Depends["minecraft"], version) {
+				continue
+			}
+
+			name := modJson.Name
+			if name == "" {
+				name = path.Base(repo)
+			}
+
+			return util.ModData{
+				Platform:     "github",
+				Slug:         path.Base(repo),
+				Name:         strings.Replace(name, " ", "-", -1),
+				ProjectId:    repo,
+				Id:           fmt.Sprint(asset.Id),
+				Version:      release.Tag_name,
+				Url:          asset.Url,
+				Filename:     asset.Name,
+				AssetPattern: pattern,
+			}, nil
+		}
+	}
+	return util.ModData{}, errors.New("failed to find matching version")
+}
+
+// GetGithubModInfo shows a repo like a mod page, its versions are the tags of releases with an asset matching the pattern
+func GetGithubModInfo(repo string, pattern string) (i ModInfo, e error) {
+	var project githubRepo
+	resp, err := githubRequest().SetResult(&project).Get(GITHUB_API_BASE + "/repos/" + repo)
+	if err != nil {
+		return ModInfo{}, err
+	}
+
+	if err1 := checkGithubResponse(resp); err1 != nil {
+		return ModInfo{}, err1
+	}
+
+	var releases []githubRelease
+	if _, err2 := githubRequest().SetResult(&releases).SetQueryParam("per_page", "30").Get(GITHUB_API_BASE + "/repos/" + repo + "/releases"); err2 != nil {
+		return ModInfo{}, err2
+	}
+
+	info := ModInfo{
+		Source:      "github",
+		Id:          project.Full_name,
+		Slug:        project.Name,
+		Name:        project.Name,
+		Description: project.Description,
+		License:     project.License.Name,
+		Links: map[string]string{
+			"Page":    project.Html_url,
+			"Website": project.Homepage,
+		},
+	}
+	if project.Has_issues {
+		info.Links["Issues"] = project.Html_url + "/issues"
+	}
+	if project.Has_wiki {
+		info.Links["Wiki"] = project.Html_url + "/wiki"
+	}
+
+	for _, release := range releases {
+		for _, asset := range release.Assets {
+			if !release.Draft && isGithubAssetMatch(asset.Name, pattern) {
+				info.Versions = append(info.Versions, release.Tag_name)
+				break
+			}
+		}
+	}
+	return info, nil
+}
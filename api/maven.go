@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+	"github.com/mrnavastar/modman/util/netutils"
+)
+
+// mavenCandidates is how many of the newest versions are downloaded looking for one that fits the game version, the one picked is not downloaded again
+const mavenCandidates = 10
+
+type mavenMetadata struct {
+	Versioning struct {
+		Latest   string   `xml:"latest"`
+		Release  string   `xml:"release"`
+		Versions []string `xml:"versions>version"`
+	} `xml:"versioning"`
+}
+
+// getMavenRepos returns the repositories to look for maven mods in, the comma separated MODMAN_MAVEN_REPOS environment variable wins over the config
+func getMavenRepos() []string {
+	repos := config.MavenRepos
+	if env := os.Getenv("MODMAN_MAVEN_REPOS"); env != "" {
+		repos = strings.Split(env, ",")
+	}
+
+	var trimmed []string
+	for _, repo := range repos {
+		if repo = strings.TrimSuffix(strings.TrimSpace(repo), "/"); repo != "" {
+			trimmed = append(trimmed, repo)
+		}
+	}
+	return trimmed
+}
+
+// configureMaven lets the local file:// repositories be read, no other files can be fetched through a url
+func configureMaven() {
+	var roots []string
+	for _, repo := range getMavenRepos() {
+		if parsed, err := url.Parse(repo); err == nil && parsed.Scheme == "file" {
+			roots = append(roots, netutils.GetFilePath(parsed))
+		}
+	}
+	netutils.SetFileRoots(roots)
+}
+
+// getMavenFile fetches a file from a repository, which can also be a local file:// one
+func getMavenFile(url string) (b []byte, e error) {
+	resp, err := client.R().Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, errors.New("maven returned " + resp.Status() + " for " + url)
+	}
+	return resp.Body(), nil
+}
+
+// getMavenVersions returns the released versions of an artifact in a repository, newest first. Snapshots are left out, they
+// can only be installed by pinning them
+func getMavenVersions(repo string, group string, artifact string) (v []string, e error) {
+	data, err := getMavenFile(repo + "/" + strings.ReplaceAll(group, ".", "/") + "/" + artifact + "/maven-metadata.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata mavenMetadata
+	if err1 := xml.Unmarshal(data, &metadata); err1 != nil {
+		return nil, err1
+	}
+
+	var versions []string
+	for _, version := range append([]string{metadata.Versioning.Release, metadata.Versioning.Latest}, metadata.Versioning.Versions...) {
+		if version != "" && !strings.HasSuffix(version, "-SNAPSHOT") && !util.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return util.CompareVersions(versions[i], versions[j]) > 0
+	})
+	return versions, nil
+}
+
+// getMavenHash reads the .sha256 or .sha1 file published next to a jar, repositories without either return an empty hash
+func getMavenHash(jarUrl string) string {
+	for _, extension := range []string{".sha256", ".sha1"} {
+		if data, err := getMavenFile(jarUrl + extension); err == nil {
+			if fields := strings.Fields(string(data)); len(fields) > 0 {
+				return strings.ToLower(fields[0])
+			}
+		}
+	}
+	return ""
+}
+
+// getMavenModJson downloads a jar to read its fabric.mod.json, maven has no metadata about game versions.
+// The download is kept, so installing the jar does not fetch it again
+func getMavenModJson(jarUrl string) (m fileutils.ModJson, e error) {
+	file, err := fileutils.InspectFile(jarUrl)
+	if err != nil {
+		return fileutils.ModJson{}, err
+	}
+	return fileutils.GetModJsonFromJar(file)
+}
+
+// GetMavenModData finds group:artifact[:version] in the configured repositories, picking the newest version whose
+// depends.minecraft allows the game version. A mod added with a version is pinned to it
+func GetMavenModData(coordinates string, loader string, version string) (m util.ModData, e error) {
+	parts := strings.Split(coordinates, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return util.ModData{}, errors.New("invalid maven coordinates")
+	}
+	group, artifact := parts[0], parts[1]
+
+	repos := getMavenRepos()
+	if len(repos) == 0 {
+		return util.ModData{}, errors.New("no maven repositories")
+	}
+
+	for _, repo := range repos {
+		versions := parts[2:]
+		if len(versions) == 0 {
+			var err error
+			versions, err = getMavenVersions(repo, group, artifact)
+			if err != nil {
+				continue
+			}
+		}
+
+		for i, mavenVersion := range versions {
+			if i == mavenCandidates {
+				break
+			}
+
+			jarUrl := repo + "/" + util.GetMavenPath(group+":"+artifact+":"+mavenVersion)
+			modJson, err1 := getMavenModJson(jarUrl)
+			if err1 != nil || !util.MatchesVersionPredicate(modJson.Depends["minecraft"], version) {
+				continue
+			}
+
+			name := modJson.Name
+			if name == "" {
+				name = artifact
+			}
+
+			return util.ModData{
+				Platform:  "maven",
+				Slug:      artifact,
+				Name:      strings.Replace(name, " ", "-", -1),
+				ProjectId: group + ":" + artifact,
+				Id:        group + ":" + artifact + ":" + mavenVersion,
+				Version:   mavenVersion,
+				Url:       jarUrl,
+				Filename:  artifact + "-" + mavenVersion + ".jar",
+				Hash:      getMavenHash(jarUrl),
+				Pinned:    len(parts) == 3,
+			}, nil
+		}
+	}
+	return util.ModData{}, errors.New("failed to find matching version")
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mrnavastar/modman/util"
+)
+
+// setupMavenRepo makes a local repository the only configured one, with files relative to it
+func setupMavenRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	repo := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(repo, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := config
+	t.Cleanup(func() {
+		config = old
+		configureMaven()
+	})
+	config = util.Config{MavenRepos: []string{"file://" + filepath.ToSlash(repo)}}
+	configureMaven()
+	return "file://" + filepath.ToSlash(repo)
+}
+
+func TestGetMavenVersions(t *testing.T) {
+	// release is stale, the list is out of order and 1.11.0 is only a snapshot
+	repo := setupMavenRepo(t, map[string]string{"com/example/teammod/maven-metadata.xml": `<metadata><versioning><latest>1.11.0-SNAPSHOT</latest><release>1.0.0</release>
+		<versions><version>1.10.0</version><version>1.0.0</version><version>1.2.0</version><version>1.11.0-SNAPSHOT</version></versions></versioning></metadata>`})
+
+	versions, err := getMavenVersions(repo, "com.example", "teammod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"1.10.0", "1.2.0", "1.0.0"}; !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected %v, got %v", expected, versions)
+	}
+
+	if _, err1 := getMavenVersions(repo, "com.example", "missing"); err1 == nil {
+		t.Error("expected an artifact without metadata to fail")
+	}
+}
+
+func TestGetMavenHash(t *testing.T) {
+	repo := setupMavenRepo(t, map[string]string{
+		"a/a.jar.sha1":   "AAAA",
+		"a/a.jar.sha256": "BBBB  a.jar\n",
+		"b/b.jar.sha1":   "CCCC\n",
+	})
+
+	for jar, expected := range map[string]string{"a/a.jar": "bbbb", "b/b.jar": "cccc", "c/c.jar": ""} {
+		if hash := getMavenHash(repo + "/" + jar); hash != expected {
+			t.Errorf("expected %q for %s, got %q", expected, jar, hash)
+		}
+	}
+}
+
+func TestMavenFileRoots(t *testing.T) {
+	setupMavenRepo(t, nil)
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := ioutil.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := getMavenFile("file://" + filepath.ToSlash(outside)); err == nil {
+		t.Error("expected a file outside the maven repositories to be refused")
+	}
+}
@@ -3,7 +3,10 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/mrnavastar/modman/util"
 )
@@ -11,15 +14,29 @@ import (
 var MODRINTH_API_BASE = "https://api.modrinth.com/v2"
 
 type modrinthProject struct {
-	Title string
-	Id    string
+	Title        string
+	Id           string
+	Slug         string
+	Project_type string
+	Description  string
+	Client_side  string
+	Server_side  string
+	Source_url   string
+	Issues_url   string
+	Wiki_url     string
+	Discord_url  string
+	License      struct {
+		Id   string
+		Name string
+	}
 }
 
 type modrinthVersion struct {
-	Id            string
-	Game_versions []string
-	Loaders       []string
-	Files         []struct {
+	Id             string
+	Version_number string
+	Game_versions  []string
+	Loaders        []string
+	Files          []struct {
 		Url      string
 		Filename string
 	}
@@ -31,7 +48,34 @@ type modrinthVersion struct {
 	}
 }
 
-func GetModrinthModData(slug string, loader string, version string) (m util.ModData, e error) {
+// isModrinthVersionMatch checks a version against the game version, mods also need the loader. Resource packs and shaders
+// work on every loader while datapacks are released under the datapack loader
+func isModrinthVersionMatch(modVersion modrinthVersion, loader string, version string, packType string) bool {
+	if !util.Contains(modVersion.Game_versions, version) {
+		return false
+	}
+
+	switch packType {
+	case "":
+		return util.Contains(modVersion.Loaders, loader)
+	case "datapack":
+		return util.Contains(modVersion.Loaders, "datapack")
+	}
+	return true
+}
+
+func hasModrinthVersion(versions []modrinthVersion, loader string, version string, packType string) bool {
+	for _, modVersion := range versions {
+		if isModrinthVersionMatch(modVersion, loader, version, packType) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetModrinthModData finds the newest release of a project for a loader and game version. Packs are found by their
+// project type when packType is empty
+func GetModrinthModData(slug string, loader string, version string, packType string) (m util.ModData, e error) {
 	var project modrinthProject
 	var versions []modrinthVersion
 
@@ -45,16 +89,43 @@ func GetModrinthModData(slug string, loader string, version string) (m util.ModD
 		return util.ModData{}, errors.New("invalid slug")
 	}
 
+	if project.Slug != "" {
+		slug = project.Slug
+	}
+
+	//Datapacks are mod projects on modrinth, any other type has to match the project
+	if packType != "" && project.Project_type != "" && packType != project.Project_type && !(packType == "datapack" && project.Project_type == "mod") {
+		return util.ModData{}, errors.New("wrong project type")
+	}
+
+	if packType == "" {
+		switch project.Project_type {
+		case "resourcepack", "shader", "datapack":
+			packType = project.Project_type
+		case "modpack":
+			return util.ModData{}, errors.New("unsupported project type")
+		}
+
+		//Datapacks are mod projects on modrinth, so a mod without a release for the loader may still have a datapack one
+		if packType == "" && !hasModrinthVersion(versions, loader, version, "") && hasModrinthVersion(versions, loader, version, "datapack") {
+			packType = "datapack"
+		}
+	}
+
 	for _, modVersion := range versions {
-		if util.Contains(modVersion.Loaders, loader) && util.Contains(modVersion.Game_versions, version) {
+		if isModrinthVersionMatch(modVersion, loader, version, packType) {
 			var modData = util.ModData{
-				Platform:  "modrinth",
-				Slug:      slug,
-				ProjectId: project.Id,
-				Id:        modVersion.Id,
-				Name:      strings.Replace(project.Title, " ", "-", -1),
-				Url:       modVersion.Files[0].Url,
-				Filename:  modVersion.Files[0].Filename,
+				Platform:   "modrinth",
+				Type:       packType,
+				Version:    modVersion.Version_number,
+				Slug:       slug,
+				ProjectId:  project.Id,
+				Id:         modVersion.Id,
+				Name:       strings.Replace(project.Title, " ", "-", -1),
+				Url:        modVersion.Files[0].Url,
+				Filename:   modVersion.Files[0].Filename,
+				ClientSide: project.Client_side,
+				ServerSide: project.Server_side,
 			}
 
 			for _, mod := range modVersion.Dependencies {
@@ -94,3 +165,123 @@ func SearchModrinth(loader string, query string) (s string, e error) {
 	}
 	return "", errors.New("no mod found")
 }
+
+type modrinthSearch struct {
+	Hits []struct {
+		Project_id  string
+		Slug        string
+		Title       string
+		Description string
+		Author      string
+		Categories  []string
+		Downloads   int
+	}
+}
+
+// getModrinthVersions returns the versions of a project for a loader and game version, newest first
+func getModrinthVersions(id string, loader string, version string) []modrinthVersion {
+	request := client.R()
+	if loader != "" {
+		request.SetQueryParam("loaders", "[\""+loader+"\"]")
+	}
+	if version != "" {
+		request.SetQueryParam("game_versions", "[\""+version+"\"]")
+	}
+
+	var versions []modrinthVersion
+	_, err := request.SetResult(&versions).Get(MODRINTH_API_BASE + "/project/" + id + "/version")
+	if err != nil {
+		return nil
+	}
+	return versions
+}
+
+func GetModrinthModInfo(slug string, loader string, version string) (i ModInfo, e error) {
+	var project modrinthProject
+	resp, err := client.R().SetResult(&project).Get(MODRINTH_API_BASE + "/project/" + slug)
+	util.Fatal(err)
+
+	if resp.StatusCode() != 200 {
+		return ModInfo{}, errors.New("invalid slug")
+	}
+
+	info := ModInfo{
+		Source:      "modrinth",
+		Id:          project.Id,
+		Slug:        project.Slug,
+		Name:        project.Title,
+		Description: project.Description,
+		License:     project.License.Name,
+		ClientSide:  project.Client_side,
+		ServerSide:  project.Server_side,
+		Links: map[string]string{
+			"Page":    "https://modrinth.com/mod/" + project.Slug,
+			"Source":  project.Source_url,
+			"Issues":  project.Issues_url,
+			"Wiki":    project.Wiki_url,
+			"Discord": project.Discord_url,
+		},
+	}
+
+	if info.License == "" {
+		info.License = project.License.Id
+	}
+
+	for _, v := range getModrinthVersions(project.Id, loader, version) {
+		info.Versions = append(info.Versions, v.Version_number)
+	}
+	return info, nil
+}
+
+// SearchModrinthMods searches modrinth for mods, only returning ones with a release for the loader and game version when they are set
+func SearchModrinthMods(query string, loader string, version string, limit int) (h []SearchHit, e error) {
+	facets := `[["project_type:mod"]`
+	if loader != "" {
+		facets += `,["categories:` + loader + `"]`
+	}
+	if version != "" {
+		facets += `,["versions:` + version + `"]`
+	}
+	facets += "]"
+
+	var search modrinthSearch
+	resp, err := client.R().SetResult(&search).Get(MODRINTH_API_BASE + "/search?query=" + url.QueryEscape(query) + "&facets=" + url.QueryEscape(facets) + "&limit=" + strconv.Itoa(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, errors.New("search failed")
+	}
+
+	//Search results only know the game versions of a project, the newest release for the loader and game version is looked up
+	//for every hit. These are plain requests, so they are cached and work offline like the search itself
+	latest := make([]string, len(search.Hits))
+	var wait sync.WaitGroup
+	for i, hit := range search.Hits {
+		wait.Add(1)
+		go func(i int, id string) {
+			defer wait.Done()
+			if versions := getModrinthVersions(id, loader, version); len(versions) > 0 {
+				latest[i] = versions[0].Version_number
+			}
+		}(i, hit.Project_id)
+	}
+	wait.Wait()
+
+	var hits []SearchHit
+	for i, hit := range search.Hits {
+		hits = append(hits, SearchHit{
+			Source:        "modrinth",
+			Id:            hit.Project_id,
+			Slug:          hit.Slug,
+			Name:          hit.Title,
+			Description:   hit.Description,
+			Authors:       []string{hit.Author},
+			Categories:    hit.Categories,
+			Downloads:     hit.Downloads,
+			LatestVersion: latest[i],
+		})
+	}
+	return hits, nil
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mrnavastar/modman/util/netutils"
+)
+
+func TestSearchModrinthMods(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		paths = append(paths, r.URL.Path)
+		mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/search":
+			// latest_version of a search hit is the newest game version the project supports, not one of its versions
+			json.NewEncoder(w).Encode(map[string]interface{}{"hits": []map[string]interface{}{
+				{"project_id": "AANobbMI", "slug": "sodium", "title": "Sodium", "author": "jellysquid3", "downloads": 100, "latest_version": "1.19.3", "versions": []string{"1.19.2", "1.19.3"}},
+				{"project_id": "P7dR8mSH", "slug": "fabric-api", "title": "Fabric API", "author": "modmuss50", "downloads": 200, "latest_version": "1.19.3", "versions": []string{"1.19.3"}},
+			}})
+		case "/project/AANobbMI/version":
+			if r.URL.Query().Get("loaders") != `["fabric"]` || r.URL.Query().Get("game_versions") != `["1.19.2"]` {
+				t.Errorf("expected the versions to be filtered by loader and game version, got %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"id": "oFAzmQcG", "version_number": "mc1.19.2-0.4.4", "game_versions": []string{"1.19.2"}, "loaders": []string{"fabric"}}})
+		case "/project/P7dR8mSH/version":
+			json.NewEncoder(w).Encode([]map[string]interface{}{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	base := MODRINTH_API_BASE
+	MODRINTH_API_BASE = server.URL
+	defer func() {
+		MODRINTH_API_BASE = base
+	}()
+
+	cache := t.TempDir()
+	netutils.SetCache(cache, false)
+	defer netutils.SetCache("", false)
+
+	hits, err := SearchModrinthMods("sodium", "fabric", "1.19.2", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].Slug != "sodium" || hits[0].LatestVersion != "mc1.19.2-0.4.4" || hits[0].Authors[0] != "jellysquid3" {
+		t.Errorf("unexpected hits %+v", hits)
+	}
+	if len(hits) == 2 && hits[1].LatestVersion != "" {
+		t.Errorf("expected no version for a project without a 1.19.2 release, got %s", hits[1].LatestVersion)
+	}
+	if len(paths) != 3 {
+		t.Errorf("expected a search and one version lookup per hit, got %v", paths)
+	}
+
+	// every request of a search is cached, so it shows the same versions offline
+	netutils.SetCache(cache, true)
+	offline, err1 := SearchModrinthMods("sodium", "fabric", "1.19.2", 10)
+	if err1 != nil || len(offline) != 2 || offline[0].LatestVersion != "mc1.19.2-0.4.4" {
+		t.Errorf("expected the same hits offline, got %+v %v", offline, err1)
+	}
+	if len(paths) != 3 {
+		t.Errorf("expected no requests offline, got %v", paths)
+	}
+}
@@ -1,18 +1,150 @@
 package api
 
-import "log"
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
 
-type versions struct {
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+)
+
+var MOJANG_MANIFEST_URL = "https://launchermeta.mojang.com/mc/game/version_manifest_v2.json"
+var MOJANG_LIBRARIES_BASE = "https://libraries.minecraft.net"
+var MOJANG_RESOURCES_BASE = "https://resources.download.minecraft.net"
+
+type McVersion struct {
+	Id          string
+	Type        string
+	Url         string
+	Time        string
+	ReleaseTime string
+}
+
+type VersionManifest struct {
 	Latest struct {
-		Release string
+		Release  string
+		Snapshot string
+	}
+	Versions []McVersion
+}
+
+type versionJson struct {
+	Downloads struct {
+		Server struct {
+			Url string
+		}
+	}
+	JavaVersion struct {
+		MajorVersion int
+	}
+}
+
+func getVersionJson(version string) (v versionJson, e error) {
+	mcVersion, err := GetMcVersion(version)
+	if err != nil {
+		return versionJson{}, err
+	}
+
+	var versionJson versionJson
+	_, err1 := client.R().SetResult(&versionJson).Get(mcVersion.Url)
+	if err1 != nil {
+		return versionJson, err1
+	}
+	return versionJson, nil
+}
+
+// GetVersionManifest returns every game version mojang knows of, newest first
+func GetVersionManifest() (m VersionManifest, e error) {
+	var manifest VersionManifest
+	resp, err := client.R().SetResult(&manifest).Get(MOJANG_MANIFEST_URL)
+	if err != nil {
+		return VersionManifest{}, err
+	}
+
+	if resp.StatusCode() != 200 {
+		return VersionManifest{}, errors.New("failed to get version manifest")
+	}
+	return manifest, nil
+}
+
+func GetMcVersion(version string) (v McVersion, e error) {
+	manifest, err := GetVersionManifest()
+	if err != nil {
+		return McVersion{}, err
+	}
+
+	for _, v := range manifest.Versions {
+		if v.Id == version {
+			return v, nil
+		}
 	}
+	return McVersion{}, errors.New("unknown version")
+}
+
+// IsMcVersion reports whether mojang knows a game version, failing to get the manifest is returned as an error instead of false
+func IsMcVersion(version string) (b bool, e error) {
+	_, err := GetMcVersion(version)
+	if err != nil && err.Error() == "unknown version" {
+		return false, nil
+	}
+	return err == nil, err
 }
 
 func GetLatestMcVersion() string {
-	var versions versions
-	_, err := client.R().SetResult(&versions).Get("https://launchermeta.mojang.com/mc/game/version_manifest_v2.json")
+	manifest, err := GetVersionManifest()
 	if err != nil {
 		log.Fatal(err)
 	}
-	return versions.Latest.Release
-}
\ No newline at end of file
+	return manifest.Latest.Release
+}
+
+// GetJavaVersion returns the major java version a game version needs to run
+func GetJavaVersion(version string) (v int, e error) {
+	versionJson, err := getVersionJson(version)
+	if err != nil {
+		return 0, err
+	}
+
+	//Versions older than 1.17 do not list a java version and all run on java 8
+	if versionJson.JavaVersion.MajorVersion == 0 {
+		return 8, nil
+	}
+	return versionJson.JavaVersion.MajorVersion, nil
+}
+
+// DownloadVersionJson saves the vanilla version json into the versions folder of .minecraft
+func DownloadVersionJson(state *fileutils.State, version string) error {
+	dir := state.DotMinecraft + "/versions/" + version
+	if _, err := os.Stat(dir + "/" + version + ".json"); err == nil {
+		return nil
+	}
+
+	mcVersion, err1 := GetMcVersion(version)
+	if err1 != nil {
+		return err1
+	}
+
+	response, err2 := client.R().Get(mcVersion.Url)
+	if err2 != nil {
+		return err2
+	}
+
+	util.Fatal(os.MkdirAll(dir, 0700))
+	return ioutil.WriteFile(dir+"/"+version+".json", response.Body(), 0644)
+}
+
+func DownloadServerJar(version string, file string) error {
+	versionJson, err := getVersionJson(version)
+	if err != nil {
+		return err
+	}
+
+	if versionJson.Downloads.Server.Url == "" {
+		return errors.New("no server jar for version")
+	}
+
+	fileutils.DownloadFile(versionJson.Downloads.Server.Url, file)
+	return nil
+}
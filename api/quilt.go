@@ -3,22 +3,34 @@ package api
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/mrnavastar/modman/util"
 	"github.com/mrnavastar/modman/util/fileutils"
 	"github.com/pterm/pterm"
 )
 
-func GetLatestQuiltLoaderVersion() string {
+var QUILT_META_BASE = "https://meta.quiltmc.org/v3"
+
+// GetQuiltLoaderVersions returns every quilt loader version, quilt meta has no stable flag so versions without a pre-release tag count as stable
+func GetQuiltLoaderVersions() []Version {
 	var loaderVersions []Version
-	_, err := client.R().SetResult(&loaderVersions).Get("https://meta.quiltmc.org/v3/versions/loader")
+	_, err := client.R().SetResult(&loaderVersions).Get(QUILT_META_BASE + "/versions/loader")
 	util.Fatal(err)
 
-	return loaderVersions[0].Version
+	for i, loaderVersion := range loaderVersions {
+		loaderVersions[i].Stable = !strings.Contains(loaderVersion.Version, "-")
+	}
+	return loaderVersions
+}
+
+func GetLatestQuiltLoaderVersion() string {
+	return GetQuiltLoaderVersions()[0].Version
 }
 
 func DownloadQuiltJson(state *fileutils.State, gameVersion string, loaderVersion string) {
-	response, err := client.R().Get("https://meta.quiltmc.org/v3/versions/loader/" + gameVersion + "/" + loaderVersion + "/profile/json")
+	response, err := client.R().Get(QUILT_META_BASE + "/versions/loader/" + gameVersion + "/" + loaderVersion + "/profile/json")
 	util.Fatal(err)
 
 	profileName := "quilt-loader-" + loaderVersion + "-" + gameVersion
@@ -34,17 +46,45 @@ func DownloadQuiltJson(state *fileutils.State, gameVersion string, loaderVersion
 	util.Fatal(err2)
 }
 
-func IsQuiltVersionSupported(version string) bool {
+type serverProfile struct {
+	MainClass string
+	Libraries []struct {
+		Name string
+		Url  string
+	}
+}
+
+// InstallQuiltServer downloads the quilt server libraries into dir and writes a quilt-server-launch.jar that starts them
+func InstallQuiltServer(gameVersion string, loaderVersion string, dir string) error {
+	var profile serverProfile
+	_, err := client.R().SetResult(&profile).Get(QUILT_META_BASE + "/versions/loader/" + gameVersion + "/" + loaderVersion + "/server/json")
+	util.Fatal(err)
+
+	var libraries []string
+	for _, library := range profile.Libraries {
+		path := "libraries/" + util.GetMavenPath(library.Name)
+		util.Fatal(os.MkdirAll(filepath.Dir(dir+"/"+path), 0700))
+
+		fileutils.DownloadFile(strings.TrimSuffix(library.Url, "/")+"/"+util.GetMavenPath(library.Name), dir+"/"+path)
+		libraries = append(libraries, path)
+	}
+	return fileutils.WriteLauncherJar(dir+"/quilt-server-launch.jar", profile.MainClass, append(libraries, "server.jar"))
+}
+
+func GetQuiltGameVersions() []string {
 	var versions []Version
-	_, err := client.R().SetResult(&versions).Get("https://meta.quiltmc.org/v3/versions/game")
+	_, err := client.R().SetResult(&versions).Get(QUILT_META_BASE + "/versions/game")
 	if err != nil {
 		pterm.Fatal.Println(err)
 	}
 
+	var gameVersions []string
 	for _, v := range versions {
-		if v.Version == version {
-			return true
-		}
+		gameVersions = append(gameVersions, v.Version)
 	}
-	return false
+	return gameVersions
+}
+
+func IsQuiltVersionSupported(version string) bool {
+	return util.Contains(GetQuiltGameVersions(), version)
 }
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrnavastar/modman/services"
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+)
+
+func TestE2EPacks(t *testing.T) {
+	dotMinecraft := setupE2E(t)
+
+	run(t, "make", "test", "fabric", "1.19.2")
+
+	// the resource pack is found by its project type, the shader is asked for
+	run(t, "install", "crisp-textures")
+	run(t, "install", "--type", "shader", "soft-shadows")
+	instance := getInstance(t, "test")
+	if pack := getMod(t, instance, "crisp-textures"); pack.Type != "resourcepack" || pack.Version != "1.2" || pack.ServerSide != "unsupported" {
+		t.Errorf("unexpected resource pack %+v", pack)
+	}
+	if shader := getMod(t, instance, "soft-shadows"); shader.Type != "shader" {
+		t.Errorf("unexpected shader %+v", shader)
+	}
+	assertExists(t, filepath.Join(dotMinecraft, "resourcepacks", "crisp-textures-1.2.zip"))
+	assertExists(t, filepath.Join(dotMinecraft, "shaderpacks", "soft-shadows-2.0.zip"))
+
+	// datapacks need a world that exists
+	run(t, "install", "taller-trees")
+	run(t, "install", "--world", "Missing", "taller-trees")
+	if _, installed := services.FindMod(getInstance(t, "test"), "taller-trees"); installed {
+		t.Error("expected the datapack to need an existing world")
+	}
+	if err := os.MkdirAll(filepath.Join(dotMinecraft, "saves", "Survival"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "install", "--world", "Survival", "taller-trees")
+	if datapack := getMod(t, getInstance(t, "test"), "taller-trees"); datapack.Type != "datapack" || datapack.World != "Survival" {
+		t.Errorf("unexpected datapack %+v", datapack)
+	}
+	assertExists(t, filepath.Join(dotMinecraft, "saves", "Survival", "datapacks", "taller-trees-1.0.zip"))
+
+	// the same datapack can go into another world, but not twice into one
+	if err := os.MkdirAll(filepath.Join(dotMinecraft, "saves", "Creative"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "install", "--world", "Creative", "taller-trees")
+	run(t, "install", "--world", "Survival", "taller-trees")
+	var worlds []string
+	for _, mod := range getInstance(t, "test").Mods {
+		if mod.Slug == "taller-trees" {
+			worlds = append(worlds, mod.World)
+		}
+	}
+	if len(worlds) != 2 {
+		t.Errorf("expected the datapack in Survival and Creative, got %v", worlds)
+	}
+	assertExists(t, filepath.Join(dotMinecraft, "saves", "Creative", "datapacks", "taller-trees-1.0.zip"))
+
+	// --type has to match the project, a mod is not a resource pack
+	run(t, "install", "--type", "resourcepack", "sodium")
+	if _, installed := services.FindMod(getInstance(t, "test"), "sodium"); installed {
+		t.Error("expected a mod project to be rejected as a resource pack")
+	}
+	assertMissing(t, filepath.Join(dotMinecraft, "resourcepacks", "sodium-fabric-mc1.19.2-0.4.4+build.18.jar"))
+
+	// packs follow the game version like mods, the ones without a 1.19.3 release are kept
+	run(t, "migrate", "--in-place", "1.19.3")
+	instance = getInstance(t, "test")
+	if pack := getMod(t, instance, "crisp-textures"); pack.Version != "1.3" {
+		t.Errorf("expected the 1.19.3 release of the resource pack, got %s", pack.Version)
+	}
+	assertExists(t, filepath.Join(dotMinecraft, "resourcepacks", "crisp-textures-1.3.zip"))
+	if kept := getMod(t, instance, "taller-trees"); kept.Version != "1.0" {
+		t.Errorf("expected the datapack without a 1.19.3 release to be kept, got %+v", kept)
+	}
+	getMod(t, instance, "soft-shadows")
+	assertExists(t, filepath.Join(dotMinecraft, "saves", "Survival", "datapacks", "taller-trees-1.0.zip"))
+
+	// the snapshot taken before migrating still uses the old release, both share .minecraft so it is kept
+	assertExists(t, filepath.Join(dotMinecraft, "resourcepacks", "crisp-textures-1.2.zip"))
+
+	// a pack is only deleted once no instance tracks it
+	run(t, "clone", "test", "copy")
+	run(t, "sel", "test")
+	run(t, "rm", "crisp-textures")
+	assertExists(t, filepath.Join(dotMinecraft, "resourcepacks", "crisp-textures-1.3.zip"))
+	run(t, "sel", "copy")
+	run(t, "rm", "crisp-textures")
+	assertMissing(t, filepath.Join(dotMinecraft, "resourcepacks", "crisp-textures-1.3.zip"))
+}
+
+func TestE2EIsolated(t *testing.T) {
+	dotMinecraft := setupE2E(t)
+
+	run(t, "make", "--isolated", "alone", "fabric", "1.19.2")
+	instance := getInstance(t, "alone")
+	if !instance.Isolated || services.GetGameDir(instance) != instance.Path {
+		t.Errorf("expected an isolated instance, got %+v", instance)
+	}
+	for _, folder := range []string{"mods", "config", "saves"} {
+		assertExists(t, filepath.Join(instance.Path, folder))
+	}
+	if profile, err := fileutils.GetProfile("alone"); err != nil || profile.GameDir != instance.Path || strings.Contains(profile.JavaArgs, "fabric.addMods") {
+		t.Errorf("expected the profile to run in the instance folder, got %+v %v", profile, err)
+	}
+
+	run(t, "install", "sodium")
+	assertExists(t, filepath.Join(instance.Path, "mods", getMod(t, getInstance(t, "alone"), "sodium").Filename))
+
+	// a failed move puts the jars moved so far back and leaves the instance shared
+	run(t, "make", "stuck", "fabric", "1.19.2")
+	stuck := getInstance(t, "stuck")
+	writeModJar(t, filepath.Join(stuck.Path, "a.jar"), `{"id": "a"}`)
+	writeModJar(t, filepath.Join(stuck.Path, "b.jar"), `{"id": "b"}`)
+	if err := os.MkdirAll(filepath.Join(stuck.Path, "mods", "b.jar", "taken"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "isolate")
+	if getInstance(t, "stuck").Isolated {
+		t.Error("expected the instance to stay shared after a failed isolate")
+	}
+	assertExists(t, filepath.Join(stuck.Path, "a.jar"))
+	assertExists(t, filepath.Join(stuck.Path, "b.jar"))
+
+	// convert an instance that shares .minecraft, taking the shared configs and worlds along
+	run(t, "make", "shared", "fabric", "1.19.2")
+	if err := os.MkdirAll(filepath.Join(dotMinecraft, "saves", "Survival"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dotMinecraft, "config"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dotMinecraft, "config", "sodium-options.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "install", "sodium", "crisp-textures")
+	run(t, "install", "--world", "Survival", "taller-trees")
+	shared := getInstance(t, "shared")
+	sodium := getMod(t, shared, "sodium")
+
+	run(t, "isolate", "--copy")
+	instance = getInstance(t, "shared")
+	if !instance.Isolated {
+		t.Fatal("expected isolate to convert the instance")
+	}
+	assertMissing(t, filepath.Join(instance.Path, sodium.Filename))
+	assertExists(t, filepath.Join(instance.Path, "mods", sodium.Filename))
+	assertExists(t, filepath.Join(instance.Path, "resourcepacks", "crisp-textures-1.2.zip"))
+	assertExists(t, filepath.Join(instance.Path, "saves", "Survival", "datapacks", "taller-trees-1.0.zip"))
+	assertExists(t, filepath.Join(instance.Path, "config", "sodium-options.json"))
+	if profile, err := fileutils.GetProfile("shared"); err != nil || profile.GameDir != instance.Path {
+		t.Errorf("expected the profile to run in the instance folder, got %+v %v", profile, err)
+	}
+
+	// the shared files stay for the instances that still use them
+	assertExists(t, filepath.Join(dotMinecraft, "resourcepacks", "crisp-textures-1.2.zip"))
+
+	run(t, "rm", "crisp-textures")
+	assertMissing(t, filepath.Join(instance.Path, "resourcepacks", "crisp-textures-1.2.zip"))
+
+	// a file that is already gone does not stop rm
+	if err := os.Remove(filepath.Join(instance.Path, "saves", "Survival", "datapacks", "taller-trees-1.0.zip")); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "rm", "taller-trees")
+	if _, installed := services.FindMod(getInstance(t, "shared"), "taller-trees"); installed {
+		t.Error("expected rm to drop a pack whose file is already gone")
+	}
+}
+
+func writeConfigFile(t *testing.T, instance util.Instance, name string, content string) {
+	t.Helper()
+	file := filepath.Join(services.GetConfigFolder(instance), name)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertConfig(t *testing.T, instance util.Instance, name string, content string) {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join(services.GetConfigFolder(instance), name))
+	if err != nil || string(data) != content {
+		t.Errorf("expected config/%s of %s to be %q, got %q %v", name, instance.Name, content, data, err)
+	}
+}
+
+func TestE2EConfigs(t *testing.T) {
+	setupE2E(t)
+
+	run(t, "make", "--isolated", "pack", "fabric", "1.19.2")
+	instance := getInstance(t, "pack")
+	options := "{\n \"quality\": \"high\",\n \"fps\": 60\n}\n"
+	writeConfigFile(t, instance, "sodium-options.json", options)
+	writeConfigFile(t, instance, "server.toml", "name = \"{{.Name}}\"\nip = \"{{.Vars.server}}\"\n")
+
+	run(t, "config", "set", "server", "play.example.com")
+	run(t, "config", "track", "config/sodium-options.json")
+	run(t, "config", "track", "--template", "server.toml")
+	instance = getInstance(t, "pack")
+	if len(instance.Configs) != 2 || !instance.Configs[0].Template {
+		t.Errorf("expected 2 tracked configs with server.toml as a template, got %+v", instance.Configs)
+	}
+	assertConfig(t, instance, "server.toml", "name = \"pack\"\nip = \"play.example.com\"\n")
+
+	// a local edit shows up in the diff against the default
+	edited := "{\n \"quality\": \"high\",\n \"fps\": 144\n}\n"
+	writeConfigFile(t, instance, "sodium-options.json", edited)
+	lines, err := services.DiffConfig(instance, "sodium-options.json")
+	if err != nil || !util.Contains(lines, "- \"fps\": 60") || !util.Contains(lines, "+ \"fps\": 144") {
+		t.Errorf("unexpected diff %q %v", lines, err)
+	}
+	run(t, "config", "ls")
+	run(t, "config", "diff")
+
+	run(t, "config", "set", "server", "other.example.com")
+	assertConfig(t, getInstance(t, "pack"), "server.toml", "name = \"pack\"\nip = \"other.example.com\"\n")
+
+	// a migrated instance keeps the local edits and renders templates for itself
+	run(t, "migrate", "1.19.3")
+	migrated := getInstance(t, "pack_Migrated")
+	assertConfig(t, migrated, "sodium-options.json", edited)
+	assertConfig(t, migrated, "server.toml", "name = \"pack_Migrated\"\nip = \"other.example.com\"\n")
+
+	// exports carry the defaults, not the local edits
+	run(t, "sel", "pack")
+	run(t, "export")
+	export := filepath.Join(fileutils.LoadAppState().WorkDir, "exports", "pack.json")
+
+	// a diff against the export only compares tracked configs, untracked files are not in exports
+	writeConfigFile(t, getInstance(t, "pack"), "untracked.json", "{}")
+	a, aConfigs, err1 := services.LoadDiffSource("pack")
+	b, bConfigs, err2 := services.LoadDiffSource(export)
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+	if diff := services.DiffInstances(a, aConfigs, b, bConfigs); len(diff.Configs) != 1 || diff.Configs[0].File != "sodium-options.json" {
+		t.Errorf("expected only the edited config to differ from the export, got %+v", diff.Configs)
+	}
+
+	// server exports get the defaults too
+	server := t.TempDir()
+	run(t, "export", "server", server)
+	assertExists(t, filepath.Join(server, "config", "sodium-options.json"))
+	if data, err3 := ioutil.ReadFile(filepath.Join(server, "config", "server.toml")); err3 != nil || string(data) != "name = \"pack\"\nip = \"other.example.com\"\n" {
+		t.Errorf("expected the rendered server.toml in the server export, got %q %v", data, err3)
+	}
+
+	setupDotMinecraft(t)
+	run(t, "import", "instance", export)
+	imported := getInstance(t, "pack")
+	assertConfig(t, imported, "sodium-options.json", options)
+	assertConfig(t, imported, "server.toml", "name = \"pack\"\nip = \"other.example.com\"\n")
+
+	// an export with a config outside of the config folder is not imported at all
+	escape := imported
+	escape.Name = "escape"
+	escape.Configs = append(escape.Configs, util.ConfigFile{Path: "../options.txt", Content: "fov:1.0\n"})
+	data, err4 := json.Marshal(escape)
+	if err4 != nil {
+		t.Fatal(err4)
+	}
+	escapeFile := filepath.Join(t.TempDir(), "escape.json")
+	if err5 := ioutil.WriteFile(escapeFile, data, 0644); err5 != nil {
+		t.Fatal(err5)
+	}
+	run(t, "import", "instance", escapeFile)
+	if _, err6 := services.GetInstance("escape"); err6 == nil {
+		t.Error("expected the export with a config outside of the config folder to be rejected")
+	}
+}
+
+func TestE2ESharedConfigs(t *testing.T) {
+	dotMinecraft := setupE2E(t)
+
+	// instances on .minecraft share its config folder, so none of them can track it
+	run(t, "make", "test", "fabric", "1.19.2")
+	if err := os.MkdirAll(filepath.Join(dotMinecraft, "config"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dotMinecraft, "config", "sodium-options.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "config", "track", "sodium-options.json")
+	if configs := getInstance(t, "test").Configs; len(configs) != 0 {
+		t.Errorf("expected a shared instance to not track configs, got %+v", configs)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mrnavastar/modman/api"
+	"github.com/mrnavastar/modman/services"
+	"github.com/mrnavastar/modman/util/fileutils"
+)
+
+// standIn serves the hosts the tests make up from this computer, which skips the fixtures: the example/betterstats github
+// repo, a plain link to coolmod-1.0.jar and a download page that is not a jar. It counts the requests for every path
+type standIn struct {
+	Url      string
+	mutex    sync.Mutex
+	requests map[string]int
+	gone     map[string]bool
+}
+
+// remove makes a path answer with 404 from now on
+func (s *standIn) remove(path string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.gone[path] = true
+}
+
+func (s *standIn) count(path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[path]
+}
+
+func newStandIn(t *testing.T) *standIn {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	s := &standIn{Url: server.URL, requests: map[string]int{}, gone: map[string]bool{}}
+
+	//Github only sends the file of an asset when asked for binary content, other hosts should not be asked for it
+	jars := t.TempDir()
+	serveJar := func(path string, name string, modJson string, asset bool) {
+		jar := filepath.Join(jars, name)
+		writeModJar(t, jar, modJson)
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			s.mutex.Lock()
+			s.requests[path]++
+			gone := s.gone[path]
+			s.mutex.Unlock()
+
+			if gone {
+				http.NotFound(w, r)
+				return
+			}
+			if asset != (r.Header.Get("Accept") == "application/octet-stream") {
+				http.Error(w, "unexpected accept header "+r.Header.Get("Accept"), http.StatusUnsupportedMediaType)
+				return
+			}
+			http.ServeFile(w, r, jar)
+		})
+	}
+
+	//v2.0.0 needs 1.19.3 while v1.5.0 is for 1.19.2, every release also has a sources jar that is never picked
+	var releases []map[string]interface{}
+	for _, release := range []struct{ tag, version, minecraft string }{{"v2.0.0", "2.0.0", ">=1.19.3"}, {"v1.5.0", "1.5.0", "1.19.2"}} {
+		var assets []map[string]interface{}
+		for i, name := range []string{"betterstats-" + release.version + ".jar", "betterstats-" + release.version + "-sources.jar"} {
+			id := 90000 + len(releases)*10 + i
+			path := "/repos/example/betterstats/releases/assets/" + fmt.Sprint(id)
+			serveJar(path, name, `{"id": "betterstats", "version": "`+release.version+`", "name": "Better Stats", "environment": "*", "depends": {"minecraft": "`+release.minecraft+`"}}`, true)
+			assets = append(assets, map[string]interface{}{"id": id, "name": name, "url": server.URL + path})
+		}
+		releases = append(releases, map[string]interface{}{"tag_name": release.tag, "name": "Better Stats " + release.tag, "assets": assets})
+	}
+	mux.HandleFunc("/repos/example/betterstats/releases", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(releases)
+	})
+
+	mux.HandleFunc("/mods/download-page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>Download coolmod</body></html>")
+	})
+	serveJar("/mods/coolmod-1.0.jar", "coolmod-1.0.jar", `{"id": "coolmod", "version": "1.0", "name": "Cool Mod", "environment": "*", "depends": {"minecraft": "1.19.x", "fabric-api": "*"}}`, false)
+	return s
+}
+
+func TestE2EGithub(t *testing.T) {
+	setupE2E(t)
+	base := api.GITHUB_API_BASE
+	t.Cleanup(func() {
+		api.GITHUB_API_BASE = base
+	})
+	standIn := newStandIn(t)
+	setEnv(t, "MODMAN_GITHUB_API_BASE", standIn.Url)
+
+	run(t, "make", "test", "fabric", "1.19.2")
+
+	// v2.0.0 needs 1.19.3, so the asset of v1.5.0 is the newest that fits
+	run(t, "install", "gh:example/betterstats")
+	mod := getMod(t, getInstance(t, "test"), "betterstats")
+	if mod.Platform != "github" || mod.Version != "v1.5.0" || mod.Filename != "betterstats-1.5.0.jar" {
+		t.Errorf("unexpected github mod %+v", mod)
+	}
+	if requests := standIn.count("/repos/example/betterstats/releases/assets/90010"); requests != 1 {
+		t.Errorf("expected the asset read for its game version to be installed without downloading it again, got %d downloads", requests)
+	}
+
+	run(t, "update")
+	if updated := getMod(t, getInstance(t, "test"), "betterstats"); updated.Version != "v1.5.0" {
+		t.Errorf("expected update to keep v1.5.0 on 1.19.2, got %s", updated.Version)
+	}
+
+	// a loader that can not be installed leaves the instance as it was
+	profile, err := fileutils.GetProfile("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileutils.RemoveProfile("test")
+	if _, err1 := services.MigrateInstance("test", "fabric", "1.19.3", true); err1 == nil {
+		t.Error("expected the migration to fail without a launcher profile")
+	}
+	instance := getInstance(t, "test")
+	if mod = getMod(t, instance, "betterstats"); instance.Version != "1.19.2" || mod.Version != "v1.5.0" {
+		t.Errorf("expected the instance to be restored, got %+v", instance)
+	}
+	assertExists(t, filepath.Join(services.GetModFolder(instance), "betterstats-1.5.0.jar"))
+	fileutils.AddProfile(profile)
+
+	run(t, "migrate", "--in-place", "1.19.3")
+	instance = getInstance(t, "test")
+	mod = getMod(t, instance, "betterstats")
+	if mod.Version != "v2.0.0" {
+		t.Errorf("expected v2.0.0 after moving to 1.19.3, got %s", mod.Version)
+	}
+	assertExists(t, filepath.Join(services.GetModFolder(instance), "betterstats-2.0.0.jar"))
+	assertMissing(t, filepath.Join(services.GetModFolder(instance), "betterstats-1.5.0.jar"))
+}
+
+func TestE2EJarSources(t *testing.T) {
+	setupE2E(t)
+	standIn := newStandIn(t)
+
+	run(t, "make", "test", "fabric", "1.19.2")
+
+	jar := filepath.Join(t.TempDir(), "localmod.jar")
+	writeModJar(t, jar, `{"id": "localmod", "version": "1.0.0", "name": "Local Mod", "environment": "*", "depends": {"minecraft": "1.19.2", "coolmod": "*"}}`)
+
+	run(t, "install", "--check-url", "url:"+standIn.Url+"/mods/coolmod-1.0.jar", "file:"+jar)
+	instance := getInstance(t, "test")
+	cool := getMod(t, instance, "coolmod")
+	if cool.Platform != "url" || cool.Version != "1.0" || cool.Hash == "" || !cool.CheckUrl {
+		t.Errorf("unexpected url mod %+v", cool)
+	}
+	if len(cool.Dependencies) != 1 || cool.Dependencies[0].Name != "fabric-api" {
+		t.Errorf("expected fabric-api as the only dependency of coolmod, got %+v", cool.Dependencies)
+	}
+	local := getMod(t, instance, "localmod")
+	if local.Platform != "file" || local.Url != jar || local.Version != "1.0.0" {
+		t.Errorf("unexpected file mod %+v", local)
+	}
+	assertExists(t, filepath.Join(services.GetModFolder(instance), "coolmod-1.0.jar"))
+	assertExists(t, filepath.Join(services.GetModFolder(instance), "localmod.jar"))
+
+	// a rebuilt local jar is picked up by update, the unchanged link is kept
+	writeModJar(t, jar, `{"id": "localmod", "version": "1.0.1", "name": "Local Mod", "environment": "*", "depends": {"minecraft": "1.19.2"}}`)
+	run(t, "update")
+	instance = getInstance(t, "test")
+	if updated := getMod(t, instance, "localmod"); updated.Version != "1.0.1" || updated.Hash == local.Hash {
+		t.Errorf("expected update to pick up the rebuilt jar, got %+v", updated)
+	}
+	if kept := getMod(t, instance, "coolmod"); kept.Hash != cool.Hash {
+		t.Errorf("expected coolmod to stay the same, got %+v", kept)
+	}
+
+	// dead links and pages that are not jars are reported without stopping the other installs or the update
+	standIn.remove("/mods/coolmod-1.0.jar")
+	run(t, "install", "url:"+standIn.Url+"/mods/missing.jar", "url:"+standIn.Url+"/mods/download-page", "sodium")
+	instance = getInstance(t, "test")
+	getMod(t, instance, "sodium")
+	if len(instance.Mods) != 3 {
+		t.Errorf("expected only sodium to be added, got %+v", instance.Mods)
+	}
+
+	run(t, "update")
+	instance = getInstance(t, "test")
+	if kept := getMod(t, instance, "coolmod"); kept.Hash != cool.Hash {
+		t.Errorf("expected coolmod to be kept while its link is dead, got %+v", kept)
+	}
+	assertExists(t, filepath.Join(services.GetModFolder(instance), "coolmod-1.0.jar"))
+}
+
+// publishMavenVersion adds a version of com.example:teammod with a .sha1 next to its jar to a local maven repository, returning the jar
+func publishMavenVersion(t *testing.T, repo string, version string, minecraft string) string {
+	t.Helper()
+	dir := filepath.Join(repo, "com", "example", "teammod", version)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	jar := filepath.Join(dir, "teammod-"+version+".jar")
+	writeModJar(t, jar, `{"id": "teammod", "version": "`+version+`", "name": "Team Mod", "environment": "*", "depends": {"minecraft": "`+minecraft+`"}}`)
+	data, err := ioutil.ReadFile(jar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err1 := ioutil.WriteFile(jar+".sha1", []byte(fmt.Sprintf("%x", sha1.Sum(data))), 0644); err1 != nil {
+		t.Fatal(err1)
+	}
+	return jar
+}
+
+// writeMavenMetadata lists the versions of com.example:teammod in a local maven repository, in the order given
+func writeMavenMetadata(t *testing.T, repo string, release string, latest string, versions ...string) {
+	t.Helper()
+	metadata := "<metadata><groupId>com.example</groupId><artifactId>teammod</artifactId><versioning><latest>" + latest + "</latest><release>" + release + "</release><versions>"
+	for _, v := range versions {
+		metadata += "<version>" + v + "</version>"
+	}
+	metadata += "</versions></versioning></metadata>"
+	if err := ioutil.WriteFile(filepath.Join(repo, "com", "example", "teammod", "maven-metadata.xml"), []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestE2EMaven(t *testing.T) {
+	setupE2E(t)
+
+	repo := t.TempDir()
+	publishMavenVersion(t, repo, "1.0.0", "1.19.2")
+	writeMavenMetadata(t, repo, "1.0.0", "1.0.0", "1.0.0")
+	setEnv(t, "MODMAN_MAVEN_REPOS", "file://"+repo)
+
+	run(t, "make", "test", "fabric", "1.19.2")
+	run(t, "install", "maven:com.example:teammod")
+	instance := getInstance(t, "test")
+	mod := getMod(t, instance, "teammod")
+	if mod.Platform != "maven" || mod.Version != "1.0.0" || mod.Hash == "" || mod.Pinned {
+		t.Errorf("unexpected maven mod %+v", mod)
+	}
+	assertExists(t, filepath.Join(services.GetModFolder(instance), "teammod-1.0.0.jar"))
+
+	// versions are ordered by their number, not by the stale release or the order of the metadata. 1.2.0 is for 1.19.3
+	// and the snapshot is skipped, so update takes 1.10.0 and checks it against its .sha256
+	jar := publishMavenVersion(t, repo, "1.10.0", "1.19.2")
+	publishMavenVersion(t, repo, "1.2.0", "1.19.3")
+	publishMavenVersion(t, repo, "1.11.0-SNAPSHOT", "1.19.2")
+	writeMavenMetadata(t, repo, "1.0.0", "1.11.0-SNAPSHOT", "1.10.0", "1.0.0", "1.2.0", "1.11.0-SNAPSHOT")
+	data, err := ioutil.ReadFile(jar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err1 := ioutil.WriteFile(jar+".sha256", []byte(fmt.Sprintf("%x  teammod-1.10.0.jar", sha256.Sum256(data))), 0644); err1 != nil {
+		t.Fatal(err1)
+	}
+
+	run(t, "update")
+	instance = getInstance(t, "test")
+	if updated := getMod(t, instance, "teammod"); updated.Version != "1.10.0" || len(updated.Hash) != 64 {
+		t.Errorf("expected update to install 1.10.0 with its sha256, got %+v", updated)
+	}
+	assertExists(t, filepath.Join(services.GetModFolder(instance), "teammod-1.10.0.jar"))
+	assertMissing(t, filepath.Join(services.GetModFolder(instance), "teammod-1.0.0.jar"))
+
+	// a jar that does not match its published hash is refused and the installed version is kept
+	jar = publishMavenVersion(t, repo, "1.12.0", "1.19.2")
+	if err2 := ioutil.WriteFile(jar+".sha1", []byte("0000000000000000000000000000000000000000"), 0644); err2 != nil {
+		t.Fatal(err2)
+	}
+	writeMavenMetadata(t, repo, "1.12.0", "1.12.0", "1.0.0", "1.10.0", "1.12.0")
+	run(t, "update")
+	instance = getInstance(t, "test")
+	if kept := getMod(t, instance, "teammod"); kept.Version != "1.10.0" {
+		t.Errorf("expected 1.10.0 to be kept after a hash mismatch, got %s", kept.Version)
+	}
+	assertExists(t, filepath.Join(services.GetModFolder(instance), "teammod-1.10.0.jar"))
+	assertMissing(t, filepath.Join(services.GetModFolder(instance), "teammod-1.12.0.jar"))
+
+	// a version in the coordinates pins the mod
+	run(t, "make", "pinned", "fabric", "1.19.2")
+	run(t, "install", "maven:com.example:teammod:1.0.0")
+	run(t, "update")
+	if pinned := getMod(t, getInstance(t, "pinned"), "teammod"); pinned.Version != "1.0.0" || !pinned.Pinned {
+		t.Errorf("expected the pinned 1.0.0 to be kept, got %+v", pinned)
+	}
+
+	// file:// urls only reach into the local repositories
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err3 := ioutil.WriteFile(outside, []byte("secret"), 0644); err3 != nil {
+		t.Fatal(err3)
+	}
+	if err4 := fileutils.FetchFile("file://"+outside, filepath.Join(t.TempDir(), "copy.txt")); err4 == nil {
+		t.Error("expected a file outside the maven repositories to be refused")
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrnavastar/modman/services"
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+	"github.com/zalando/go-keyring"
+)
+
+// The end to end tests run the cli against the responses in testdata/fixtures. They are written by hand in the shape of the
+// real services, not recorded from them, and the tests assert on their made up projects and versions. Made up hosts, like a
+// github repo or a plain jar link, are served by newStandIn instead.
+
+// setupDotMinecraft creates an empty .minecraft in a temp folder and points modman at it
+func setupDotMinecraft(t *testing.T) string {
+	dotMinecraft := filepath.Join(t.TempDir(), ".minecraft")
+	if err := os.MkdirAll(dotMinecraft, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dotMinecraft, "launcher_profiles.json"), []byte(`{"profiles":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fileutils.Setup(dotMinecraft)
+	return dotMinecraft
+}
+
+// setEnv sets an environment variable until the test is done, t.Setenv needs a newer go
+func setEnv(t *testing.T, key string, value string) {
+	t.Helper()
+	old, found := os.LookupEnv(key)
+	t.Cleanup(func() {
+		if found {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+	os.Setenv(key, value)
+}
+
+func setupE2E(t *testing.T) string {
+	keyring.MockInit()
+	setEnv(t, "HOME", t.TempDir())
+
+	fixtures, err := filepath.Abs("testdata/fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setEnv(t, "MODMAN_REPLAY_FIXTURES", fixtures)
+	return setupDotMinecraft(t)
+}
+
+func run(t *testing.T, args ...string) {
+	t.Helper()
+	if err := newApp().Run(append([]string{"modman"}, args...)); err != nil {
+		t.Fatalf("modman %v: %v", args, err)
+	}
+}
+
+func getInstance(t *testing.T, name string) util.Instance {
+	t.Helper()
+	instance, err := services.GetInstance(name)
+	if err != nil {
+		t.Fatalf("instance %s: %v", name, err)
+	}
+	return instance
+}
+
+func getMod(t *testing.T, instance util.Instance, slug string) util.ModData {
+	t.Helper()
+	for _, mod := range instance.Mods {
+		if mod.Slug == slug {
+			return mod
+		}
+	}
+	t.Fatalf("%s is not installed in %s", slug, instance.Name)
+	return util.ModData{}
+}
+
+func assertExists(t *testing.T, file string) {
+	t.Helper()
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("expected %s to exist", file)
+	}
+}
+
+func assertMissing(t *testing.T, file string) {
+	t.Helper()
+	if _, err := os.Stat(file); err == nil {
+		t.Errorf("expected %s to be gone", file)
+	}
+}
+
+// writeModJar writes a jar holding only a fabric.mod.json
+func writeModJar(t *testing.T, file string, modJson string) {
+	t.Helper()
+	out, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	writer := zip.NewWriter(out)
+	entry, err1 := writer.Create("fabric.mod.json")
+	if err1 != nil {
+		t.Fatal(err1)
+	}
+	if _, err2 := entry.Write([]byte(modJson)); err2 != nil {
+		t.Fatal(err2)
+	}
+	if err3 := writer.Close(); err3 != nil {
+		t.Fatal(err3)
+	}
+}
+
+func TestE2E(t *testing.T) {
+	dotMinecraft := setupE2E(t)
+
+	run(t, "make", "test", "fabric", "1.19.2")
+	instance := getInstance(t, "test")
+	if instance.LoaderVersion != "0.14.11" {
+		t.Errorf("expected the latest fabric loader, got %s", instance.LoaderVersion)
+	}
+	assertExists(t, filepath.Join(dotMinecraft, "versions", "fabric-loader-0.14.11-1.19.2", "fabric-loader-0.14.11-1.19.2.json"))
+	if profile, err := fileutils.GetProfile("test"); err != nil || profile.LastVersionId != "fabric-loader-0.14.11-1.19.2" {
+		t.Errorf("expected a launcher profile for the instance, got %+v %v", profile, err)
+	}
+	if fileutils.LoadAppState().ActiveInstance != "test" {
+		t.Error("expected make to select the new instance")
+	}
+
+	run(t, "install", "sodium", "fabric-api")
+	instance = getInstance(t, "test")
+	sodium := getMod(t, instance, "sodium")
+	if sodium.Version != "mc1.19.2-0.4.4" || sodium.ClientSide != "required" {
+		t.Errorf("unexpected sodium data %+v", sodium)
+	}
+	getMod(t, instance, "fabric-api")
+	assertExists(t, filepath.Join(services.GetModFolder(instance), sodium.Filename))
+
+	// pretend sodium is out of date so update has something to do
+	state := fileutils.LoadAppState()
+	for i, mod := range state.Instances[0].Mods {
+		if mod.Slug == "sodium" {
+			state.Instances[0].Mods[i].Id = "outdated"
+			state.Instances[0].Mods[i].Filename = "sodium-outdated.jar"
+		}
+	}
+	fileutils.SaveAppState(state)
+	if err := fileutils.CopyFile(filepath.Join(services.GetModFolder(instance), sodium.Filename), filepath.Join(services.GetModFolder(instance), "sodium-outdated.jar")); err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, "update")
+	instance = getInstance(t, "test")
+	if updated := getMod(t, instance, "sodium"); updated.Id != sodium.Id {
+		t.Errorf("expected update to install %s, got %s", sodium.Id, updated.Id)
+	}
+	assertMissing(t, filepath.Join(services.GetModFolder(instance), "sodium-outdated.jar"))
+	assertExists(t, filepath.Join(services.GetModFolder(instance), sodium.Filename))
+
+	run(t, "loader", "set", "0.14.10")
+	run(t, "export")
+	export := filepath.Join(dotMinecraft, "modman", "exports", "test.json")
+	assertExists(t, export)
+
+	// the lockfile matches until a jar is swapped behind modman's back
+	run(t, "lock")
+	lock := filepath.Join(dotMinecraft, "modman", "exports", "test.lock")
+	live, liveConfigs, err := services.LoadDiffSource("test")
+	locked, lockedConfigs, err1 := services.LoadDiffSource(lock)
+	if err != nil || err1 != nil {
+		t.Fatal(err, err1)
+	}
+	if diff := services.DiffInstances(locked, lockedConfigs, live, liveConfigs); len(diff.Changed) != 0 || len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("expected the instance to match its lockfile, got %+v", diff)
+	}
+
+	jar := filepath.Join(services.GetModFolder(instance), sodium.Filename)
+	original, err2 := ioutil.ReadFile(jar)
+	if err2 != nil {
+		t.Fatal(err2)
+	}
+	writeModJar(t, jar, `{"id": "sodium"}`)
+	live, liveConfigs, _ = services.LoadDiffSource("test")
+	if diff := services.DiffInstances(locked, lockedConfigs, live, liveConfigs); len(diff.Changed) != 1 || !strings.HasSuffix(diff.Changed[0].NewVersion, "(different file)") {
+		t.Errorf("expected the swapped sodium jar to differ from the lockfile, got %+v", diff.Changed)
+	}
+	run(t, "diff", lock, "test")
+	if err3 := ioutil.WriteFile(jar, original, 0644); err3 != nil {
+		t.Fatal(err3)
+	}
+
+	run(t, "migrate", "--loader", "quilt", "1.19.3")
+	migrated := getInstance(t, "test_Migrated")
+	if migrated.Loader != "quilt" || migrated.Version != "1.19.3" || migrated.LoaderVersion != "0.17.8" {
+		t.Errorf("unexpected migrated instance %+v", migrated)
+	}
+	if mod := getMod(t, migrated, "sodium"); mod.Id != "rAfhHfow" {
+		t.Errorf("expected the fabric 1.19.3 release of sodium, got %s", mod.Id)
+	}
+	getMod(t, migrated, "qsl")
+	for _, mod := range migrated.Mods {
+		if mod.Slug == "fabric-api" {
+			t.Error("expected fabric-api to be replaced by qsl")
+		}
+	}
+	if fileutils.LoadAppState().ActiveInstance != "test_Migrated" {
+		t.Error("expected migrate to select the migrated instance")
+	}
+
+	// import the export into a fresh .minecraft
+	imported := setupDotMinecraft(t)
+	run(t, "import", "instance", export)
+	instance = getInstance(t, "test")
+	if len(instance.Mods) != 2 {
+		t.Errorf("expected 2 imported mods, got %d", len(instance.Mods))
+	}
+	for _, mod := range instance.Mods {
+		assertExists(t, filepath.Join(services.GetModFolder(instance), mod.Filename))
+	}
+	if instance.LoaderVersion != "0.14.10" || !instance.LoaderPinned {
+		t.Errorf("expected the import to keep the pinned loader, got %s pinned %v", instance.LoaderVersion, instance.LoaderPinned)
+	}
+	if profile, err4 := fileutils.GetProfile("test"); err4 != nil || profile.LastVersionId != "fabric-loader-0.14.10-1.19.2" {
+		t.Errorf("expected the profile to use the pinned loader, got %+v %v", profile, err4)
+	}
+
+	// importing over an instance that exists fails instead of adding the mods to it
+	if _, err4 := services.ImportInstance(export); err4 == nil {
+		t.Error("expected importing over an existing instance to fail")
+	}
+	assertExists(t, filepath.Join(imported, "versions", "fabric-loader-0.14.11-1.19.2", "fabric-loader-0.14.11-1.19.2.json"))
+
+	// clone only creates new folders and never touches one modman does not track
+	untracked := filepath.Join(imported, "modman", "instances", "untracked")
+	if err := os.MkdirAll(untracked, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(untracked, "notes.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dst := range []string{"untracked", "../outside", ".."} {
+		if err := services.CloneInstance("test", dst); err == nil {
+			t.Errorf("expected cloning to %s to fail", dst)
+		}
+	}
+	assertExists(t, filepath.Join(untracked, "notes.txt"))
+	assertMissing(t, filepath.Join(imported, "modman", "outside"))
+
+	// vanilla instances have no loader to check the version against
+	run(t, "make", "vanilla", "", "1.19.2")
+	if vanilla := getInstance(t, "vanilla"); vanilla.Loader != "" || vanilla.Version != "1.19.2" {
+		t.Errorf("unexpected vanilla instance %+v", vanilla)
+	}
+}
+
+func TestE2EOffline(t *testing.T) {
+	setupE2E(t)
+
+	run(t, "make", "test", "fabric", "1.19.2")
+	run(t, "install", "sodium")
+
+	// everything make and install fetched is cached, so the same steps work for a second instance without any network
+	setEnv(t, "MODMAN_REPLAY_FIXTURES", t.TempDir())
+
+	run(t, "--offline", "make", "offline", "fabric", "1.19.2")
+	run(t, "--offline", "install", "sodium")
+	getMod(t, getInstance(t, "offline"), "sodium")
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/mrnavastar/modman/services"
 	"github.com/mrnavastar/modman/util"
 	"github.com/mrnavastar/modman/util/fileutils"
+	"github.com/mrnavastar/modman/util/netutils"
 	"github.com/pterm/pterm"
 	"github.com/urfave/cli/v2"
 )
@@ -22,10 +26,115 @@ type failedMod struct {
 	Slug   string
 }
 
-func main() {
-	app := &cli.App{
+// checkVersion prints why a game version can not be used with a loader
+func checkVersion(loader string, version string) bool {
+	err := api.CheckVersion(loader, version)
+	if err == nil {
+		return true
+	}
+
+	switch err.Error() {
+	case "unknown version":
+		pterm.Error.Println(version + " is not a minecraft version ~ modman versions --snapshots")
+	case "unknown loader":
+		pterm.Error.Println("Unknown loader " + loader + " ~ Use fabric or quilt")
+	case "version not supported":
+		if loader == "quilt" {
+			pterm.Error.Println("Version not supported by quilt ~ Lowest supported is 22w14a (1.18.2)")
+		} else {
+			pterm.Error.Println("Version not supported by fabric ~ Lowest supported is 18w43b (1.14)")
+		}
+	default:
+		pterm.Error.Println("Failed to check " + version + ": " + err.Error())
+	}
+	return false
+}
+
+// printMigrationReport shows which mods can follow an instance to another version
+func printMigrationReport(results []services.MigrationResult) {
+	fmt.Println()
+	var mods [][]string
+	mods = append(mods, []string{"Name", "Current", "Target", "Status"})
+	for _, result := range results {
+		name := result.Mod.Name
+		if name == "" {
+			name = result.Target.Name
+		}
+
+		status := result.Status
+		switch result.Status {
+		case "compatible":
+			status = pterm.FgGreen.Sprint(status)
+		case "missing":
+			status = pterm.FgRed.Sprint(status)
+		case "fabric only", "manual":
+			status = pterm.FgYellow.Sprint(status)
+		case "replaced", "merged":
+			status = pterm.FgYellow.Sprint(status + " by " + result.Target.Name)
+		}
+		mods = append(mods, []string{name, result.Mod.Version, result.Target.Filename, status})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(mods).Render()
+	fmt.Println()
+}
+
+// printManualDownloads lists the mods of an instance that have to be downloaded by hand
+func printManualDownloads(instance util.Instance) {
+	if len(instance.ManualMods) == 0 {
+		return
+	}
+
+	pterm.Warning.Println("Manual downloads required ~ download these files then run modman provide <mod> <file> or modman provide --watch")
+	fmt.Println()
+	var mods [][]string
+	mods = append(mods, []string{"Name", "File", "Download page"})
+	for _, mod := range instance.ManualMods {
+		mods = append(mods, []string{mod.Name, mod.Filename, api.GetCurseFileUrl(mod)})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(mods).Render()
+	fmt.Println()
+}
+
+// checkJava warns when no java runtime fits the game version of an instance
+func checkJava(instance util.Instance) {
+	required, err := api.GetJavaVersion(instance.Version)
+	if err != nil {
+		return
+	}
+
+	if _, found := services.GetMatchingJava(instance, required); !found {
+		pterm.Warning.Println(instance.Version + " needs java " + fmt.Sprint(required) + " but none was found ~ modman java set --path <java>")
+	}
+}
+
+// newApp builds the cli, the end to end tests run it the same way main does
+func newApp() *cli.App {
+	return &cli.App{
 		Name:  "ModMan",
 		Usage: "Manage your mods with ease",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "offline", Usage: "only use metadata and files modman has cached, for playing without internet"},
+		},
+		Before: func(c *cli.Context) error {
+			api.Configure(fileutils.LoadConfig())
+			if dir := os.Getenv("MODMAN_RECORD_FIXTURES"); dir != "" {
+				netutils.SetFixtures(dir, true)
+			} else if dir := os.Getenv("MODMAN_REPLAY_FIXTURES"); dir != "" {
+				netutils.SetFixtures(dir, false)
+			}
+
+			if workDir, err := fileutils.GetWorkDir(); err == nil {
+				netutils.SetCache(workDir+"/cache", c.Bool("offline"))
+			} else if c.Bool("offline") {
+				pterm.Error.Println("Modman has to be set up before it can run offline ~ modman init")
+				os.Exit(1)
+			}
+			return nil
+		},
+		After: func(c *cli.Context) error {
+			fileutils.RemoveInspectedFiles()
+			return nil
+		},
 		Commands: []*cli.Command{
 			{
 				Name:        "init",
@@ -62,14 +171,19 @@ func main() {
 
 					fmt.Println()
 					var instances [][]string
-					instances = append(instances, []string{" ", "Name", "Loader", "Version", "Mods"})
+					instances = append(instances, []string{" ", "Name", "Type", "Loader", "Version", "Mods"})
 					for _, instance := range state.Instances {
 						var prefix string
 						if state.ActiveInstance == instance.Name {
 							prefix = ">"
 						}
 
-						instances = append(instances, []string{prefix, instance.Name, instance.Loader, instance.Version, fmt.Sprint(len(instance.Mods))})
+						instanceType := "client"
+						if instance.Server {
+							instanceType = "server"
+						}
+
+						instances = append(instances, []string{prefix, instance.Name, instanceType, instance.Loader, instance.Version, fmt.Sprint(len(instance.Mods))})
 					}
 					pterm.DefaultTable.WithHasHeader().WithData(pterm.TableData(instances)).Render()
 					fmt.Println()
@@ -78,8 +192,12 @@ func main() {
 			},
 			{
 				Name:        "make",
-				Usage:       "make [name] [loader] [mc version]",
+				Usage:       "make [--server] [--isolated] [name] [loader] [mc version]",
 				Description: "Create a new instance",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "server", Usage: "create a dedicated server instance"},
+					&cli.BoolFlag{Name: "isolated", Usage: "give the instance its own mods, configs and saves instead of sharing .minecraft"},
+				},
 				Action: func(c *cli.Context) error {
 					name := c.Args().Get(0)
 					loader := c.Args().Get(1)
@@ -89,23 +207,90 @@ func main() {
 						version = api.GetLatestMcVersion()
 					}
 
-					if loader == "fabric" && !api.IsFabricVersionSupported(version) {
-						pterm.Error.Println("Version not supported ~ Lowest supported is 18w43b (1.14)")
-						return nil
-					} else if loader == "quilt" && !api.IsQuiltVersionSupported(version) {
-						pterm.Error.Println("Version not supported ~ Lowest supported is 22w14a (1.18.2)")
+					if !checkVersion(loader, version) {
 						return nil
 					}
 
 					pterm.Info.Println("Creating " + name)
-					err1 := services.CreateInstance(name, loader, version)
-					if err1 != nil {
-						pterm.Error.Println("Instance with that name already exists")
-						return nil
+					if c.Bool("server") {
+						err1 := services.CreateServerInstance(name, loader, version)
+						if err1 != nil {
+							if err1.Error() == "already instance with that name" {
+								pterm.Error.Println("Instance with that name already exists")
+							} else {
+								pterm.Error.Println(err1)
+							}
+							return nil
+						}
+					} else {
+						err1 := services.CreateInstance(name, loader, version)
+						if err1 != nil {
+							pterm.Error.Println("Instance with that name already exists")
+							return nil
+						}
+
+						if c.Bool("isolated") {
+							if err2 := services.IsolateInstance(name, false); err2 != nil {
+								pterm.Error.Println("Failed to isolate " + name + ": " + err2.Error())
+								pterm.Warning.Println("Created " + name + " sharing .minecraft instead ~ modman isolate")
+								services.SetActiveInstance(name)
+								return nil
+							}
+						}
 					}
 
 					pterm.Success.Println("Created " + name)
 					services.SetActiveInstance(name)
+
+					instance, _ := services.GetInstance(name)
+					checkJava(instance)
+					return nil
+				},
+			},
+			{
+				Name:        "versions",
+				Usage:       "versions [--snapshots] [--loader fabric]",
+				Description: "List minecraft versions",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "snapshots", Usage: "include snapshots"},
+					&cli.StringFlag{Name: "loader", Usage: "only show versions supported by a loader"},
+				},
+				Action: func(c *cli.Context) error {
+					manifest, err := api.GetVersionManifest()
+					if err != nil {
+						pterm.Error.Println("Failed to get the version manifest")
+						return nil
+					}
+
+					var supported []string
+					if c.String("loader") != "" {
+						supported, err = api.GetLoaderGameVersions(c.String("loader"))
+						if err != nil {
+							pterm.Error.Println("Unknown loader " + c.String("loader") + " ~ Use fabric or quilt")
+							return nil
+						}
+					}
+
+					fmt.Println()
+					var versions [][]string
+					versions = append(versions, []string{"Version", "Type", "Released"})
+					for _, version := range manifest.Versions {
+						if version.Type != "release" && !(c.Bool("snapshots") && version.Type == "snapshot") {
+							continue
+						}
+
+						if c.String("loader") != "" && !util.Contains(supported, version.Id) {
+							continue
+						}
+
+						released := version.ReleaseTime
+						if t, err1 := time.Parse(time.RFC3339, version.ReleaseTime); err1 == nil {
+							released = t.Format("2006-01-02")
+						}
+						versions = append(versions, []string{version.Id, version.Type, released})
+					}
+					pterm.DefaultTable.WithHasHeader().WithData(versions).Render()
+					fmt.Println()
 					return nil
 				},
 			},
@@ -155,12 +340,23 @@ func main() {
 			},
 			{
 				Name:        "install",
-				Usage:       "install [mod slug 1] [mod slug 2] [mod slug 3]",
-				Description: "Install mods - as many as you like. Curseforge slugs marked with c: at the start. Ex: c:sodium",
+				Usage:       "install [--type resourcepack | shader | datapack] [--world name] [--pattern *-fabric-*.jar] [--check-url] [mod slug 1] [mod slug 2] [mod slug 3]",
+				Description: "Install mods, resource packs, shaders and datapacks - as many as you like. Packs are found by their project type unless --type is given. Curseforge slugs marked with c: at the start. Ex: c:sodium. Github releases marked with gh: Ex: gh:owner/repo. Jars from a link or this computer marked with url: or file: Ex: file:mods/mod.jar. Maven artifacts marked with maven: Ex: maven:com.example:mod[:1.0.0]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "pattern", Usage: "file name pattern of the release asset to use for gh: mods"},
+					&cli.BoolFlag{Name: "check-url", Usage: "download url: mods again on update to look for a changed jar"},
+					&cli.StringFlag{Name: "type", Usage: "install packs of this type: resourcepack, shader or datapack"},
+					&cli.StringFlag{Name: "world", Usage: "world to install datapacks into, servers default to their own world"},
+				},
 				Action: func(c *cli.Context) error {
 					args := c.Args()
 					state := fileutils.LoadAppState()
 
+					if c.String("type") != "" && !util.Contains(services.PackTypes, c.String("type")) {
+						pterm.Error.Println("Unknown type " + c.String("type") + ", use resourcepack, shader or datapack")
+						return nil
+					}
+
 					instance, err1 := services.GetInstance(state.ActiveInstance)
 					if err1 != nil {
 						pterm.Error.Println("Must select an instance to modify ~ modman sel <name>")
@@ -172,7 +368,7 @@ func main() {
 					for i := 0; i < len(mods); i++ {
 						mod := mods[i]
 
-						err2 := services.AddMod(&instance, mod, util.ModData{}, false)
+						err2 := services.AddMod(&instance, mod, util.ModData{AssetPattern: c.String("pattern"), CheckUrl: c.Bool("check-url"), Type: c.String("type"), World: c.String("world")}, false)
 						if err2 != nil {
 							if err2.Error() == "mod already added" {
 								pterm.Info.Println(mod + " has already been added")
@@ -196,6 +392,59 @@ func main() {
 							if err2.Error() == "failed to find matching version" {
 								pterm.Error.Println(mod + " does not have a release for " + instance.Version)
 							}
+
+							if err2.Error() == "invalid url" || err2.Error() == "file not found" || err2.Error() == "not a fabric mod" || err2.Error() == "hash mismatch" || strings.HasPrefix(err2.Error(), "failed to download") {
+								pterm.Error.Println("Could not install " + mod + ": " + err2.Error())
+								continue
+							}
+
+							if err2.Error() == "no world chosen" {
+								pterm.Error.Println(mod + " is a datapack, choose the world to put it in ~ modman install --world <name> " + mod)
+								continue
+							}
+
+							if err2.Error() == "world not found" {
+								pterm.Error.Println("Could not find the world " + c.String("world") + " in saves")
+								continue
+							}
+
+							if err2.Error() == "wrong project type" {
+								pterm.Error.Println(mod + " is not a " + c.String("type") + ", install it without --type")
+								continue
+							}
+
+							if err2.Error() == "unsupported project type" {
+								pterm.Error.Println(mod + " is a modpack, only mods, resource packs, shaders and datapacks can be installed")
+								continue
+							}
+
+							if err2.Error() == "invalid maven coordinates" {
+								pterm.Error.Println("Could not install " + mod + ": use maven:group:artifact or maven:group:artifact:version")
+								continue
+							}
+
+							if err2.Error() == "no maven repositories" {
+								pterm.Error.Println("No maven repositories to look in ~ modman settings set MavenRepos <url,url>")
+								continue
+							}
+
+							if err2.Error() == "github token rejected" {
+								pterm.Error.Println("Github rejected your token ~ modman settings set GithubToken <token>")
+								continue
+							}
+
+							if err2.Error() == "curseforge api key missing" {
+								pterm.Error.Println("Curseforge needs an api key ~ modman settings set CurseApiKey <key>")
+								continue
+							}
+
+							if err2.Error() == "distribution disabled" {
+								continue
+							}
+
+							if err2.Error() == "client only mod" {
+								pterm.Warning.Println(mod + " is client only, skipping it on a server instance")
+							}
 						}
 					}
 
@@ -207,7 +456,7 @@ func main() {
 						input = strings.Replace(input, "\n", "", -1)
 
 						if strings.EqualFold(input, "y") || input == "" {
-							err3 := services.AddMod(&instance, mod.Slug, util.ModData{}, false)
+							err3 := services.AddMod(&instance, mod.Slug, util.ModData{Type: c.String("type"), World: c.String("world")}, false)
 							if err3 != nil {
 								if err3.Error() == "mod already added" {
 									pterm.Error.Println(mod.Slug + " has already been added")
@@ -217,6 +466,262 @@ func main() {
 							}
 						}
 					}
+
+					for _, mod := range services.ScanForManualMods(&instance, services.GetDownloadsFolder()) {
+						pterm.Info.Println("Used " + mod.Filename + " from your downloads folder")
+					}
+					printManualDownloads(instance)
+					return services.SaveInstance(instance)
+				},
+			},
+			{
+				Name:        "search",
+				Usage:       "search [--pick] [query]",
+				Description: "Search modrinth and curseforge for mods that work on the selected instance",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "pick", Aliases: []string{"p"}, Usage: "choose mods from the results to install"},
+					&cli.IntFlag{Name: "limit", Value: 10, Usage: "results per platform"},
+				},
+				Action: func(c *cli.Context) error {
+					query := strings.Join(c.Args().Slice(), " ")
+					state := fileutils.LoadAppState()
+					instance, err := services.GetInstance(state.ActiveInstance)
+					if err != nil && c.Bool("pick") {
+						pterm.Error.Println("Must select an instance to install to ~ modman sel <name>")
+						return nil
+					}
+
+					var hits []api.SearchHit
+					modrinthHits, err1 := api.SearchModrinthMods(query, instance.Loader, instance.Version, c.Int("limit"))
+					if err1 != nil {
+						pterm.Warning.Println("Failed to search modrinth")
+					}
+					hits = append(hits, modrinthHits...)
+
+					curseHits, err2 := api.SearchCurseMods(query, instance.Loader, instance.Version, c.Int("limit"))
+					if err2 != nil {
+						if err2.Error() == "curseforge api key missing" {
+							pterm.Warning.Println("Skipped curseforge, no api key set ~ modman settings set CurseApiKey <key>")
+						} else {
+							pterm.Warning.Println("Failed to search curseforge")
+						}
+					}
+					hits = append(hits, curseHits...)
+
+					if len(hits) == 0 {
+						pterm.Info.Println("No mods found for " + query)
+						return nil
+					}
+
+					sort.SliceStable(hits, func(i, j int) bool {
+						return hits[i].Downloads > hits[j].Downloads
+					})
+
+					fmt.Println()
+					var results [][]string
+					results = append(results, []string{"#", "Name", "Slug", "Source", "Downloads", "Authors", "Categories", "Latest"})
+					for i, hit := range hits {
+						results = append(results, []string{fmt.Sprint(i + 1), hit.Name, hit.Slug, hit.Source, fmt.Sprint(hit.Downloads), strings.Join(hit.Authors, ", "), strings.Join(hit.Categories, ", "), hit.LatestVersion})
+					}
+					pterm.DefaultTable.WithHasHeader().WithData(results).Render()
+					fmt.Println()
+
+					if !c.Bool("pick") {
+						return nil
+					}
+
+					reader := bufio.NewReader(os.Stdin)
+					pterm.Info.Println("Enter the numbers of the mods to install, ex: 1 3 4")
+					pterm.FgDarkGray.Print(">>> ")
+					input, _ := reader.ReadString('\n')
+
+					for _, field := range strings.Fields(input) {
+						i, err3 := strconv.Atoi(field)
+						if err3 != nil || i < 1 || i > len(hits) {
+							pterm.Error.Println(field + " is not a result number")
+							continue
+						}
+
+						hit := hits[i-1]
+						arg := hit.Slug
+						if hit.Source == "curse" {
+							arg = "c:" + hit.Id
+						}
+
+						err4 := services.AddMod(&instance, arg, util.ModData{}, false)
+						if err4 != nil {
+							if err4.Error() == "mod already added" {
+								pterm.Info.Println(hit.Name + " has already been added")
+							} else {
+								pterm.Error.Println("Failed to install " + hit.Name + ": " + err4.Error())
+							}
+						}
+					}
+					return services.SaveInstance(instance)
+				},
+			},
+			{
+				Name:        "info",
+				Usage:       "info [mod slug | id]",
+				Description: "Show details about a mod. Curseforge slugs marked with c: at the start. Ex: c:sodium. Github repos marked with gh: Ex: gh:owner/repo",
+				Action: func(c *cli.Context) error {
+					arg := c.Args().Get(0)
+					state := fileutils.LoadAppState()
+					instance, _ := services.GetInstance(state.ActiveInstance)
+
+					mod, installed := services.FindMod(instance, strings.TrimPrefix(strings.Replace(arg, "c:", "", -1), "gh:"))
+					if !installed {
+						mod = util.ModData{Platform: "modrinth", ProjectId: arg, Name: arg}
+						if _, err := strconv.Atoi(strings.Replace(arg, "c:", "", -1)); err == nil || strings.HasPrefix(arg, "c:") {
+							mod = util.ModData{Platform: "curse", ProjectId: strings.Replace(arg, "c:", "", -1), Name: arg}
+						}
+						if strings.HasPrefix(arg, "gh:") {
+							mod = util.ModData{Platform: "github", ProjectId: strings.TrimPrefix(arg, "gh:"), Name: arg}
+						}
+					}
+
+					var info api.ModInfo
+					var err error
+					if mod.Platform == "url" || mod.Platform == "file" || mod.Platform == "maven" {
+						info = api.ModInfo{Source: mod.Platform, Id: mod.ProjectId, Slug: mod.Slug, Name: mod.Name, ClientSide: mod.ClientSide, ServerSide: mod.ServerSide, Links: map[string]string{"Page": mod.Url}, Versions: []string{mod.Version}}
+						if modJson, err1 := services.GetInstalledModJson(instance, mod); err1 == nil {
+							info.Description = modJson.Description
+						}
+					} else if mod.Platform == "github" {
+						info, err = api.GetGithubModInfo(mod.ProjectId, mod.AssetPattern)
+					} else if mod.Platform == "curse" {
+						info, err = api.GetCurseModInfo(mod.ProjectId, instance.Loader, instance.Version)
+					} else {
+						info, err = api.GetModrinthModInfo(mod.ProjectId, instance.Loader, instance.Version)
+					}
+					if err != nil {
+						pterm.Error.Println("Could not find mod under " + arg)
+						return nil
+					}
+
+					pterm.DefaultSection.Println(info.Name)
+					pterm.Println(info.Description)
+					fmt.Println()
+
+					details := [][]string{
+						{"Source", info.Source},
+						{"Slug", info.Slug},
+						{"Id", info.Id},
+						{"License", info.License},
+						{"Client side", info.ClientSide},
+						{"Server side", info.ServerSide},
+					}
+					for _, name := range []string{"Page", "Website", "Source", "Issues", "Wiki", "Discord"} {
+						if link := info.Links[name]; link != "" {
+							details = append(details, []string{name + " link", link})
+						}
+					}
+					pterm.DefaultTable.WithData(details).Render()
+
+					if instance.Version != "" {
+						pterm.DefaultSection.WithLevel(2).Println("Versions for " + instance.Loader + " " + instance.Version)
+						if len(info.Versions) == 0 {
+							pterm.Warning.Println("No versions for " + instance.Version)
+						} else {
+							pterm.Println(strings.Join(info.Versions, ", "))
+						}
+
+						pterm.DefaultSection.WithLevel(2).Println("Dependencies")
+						mod.ProjectId = info.Id
+						tree := services.GetDependencyTree(mod, instance.Loader, instance.Version, 3)
+						if len(tree.Dependencies) == 0 {
+							pterm.Println("None")
+						} else {
+							var list pterm.LeveledList
+							var walk func(node services.DependencyNode, level int)
+							walk = func(node services.DependencyNode, level int) {
+								list = append(list, pterm.LeveledListItem{Level: level, Text: node.Name})
+								for _, dep := range node.Dependencies {
+									walk(dep, level+1)
+								}
+							}
+							walk(tree, 0)
+							pterm.DefaultTree.WithRoot(pterm.NewTreeFromLeveledList(list)).Render()
+						}
+					}
+
+					if !installed {
+						return nil
+					}
+
+					pterm.DefaultSection.WithLevel(2).Println("Installed on " + instance.Name)
+					installedDetails := [][]string{{"Filename", mod.Filename}, {"Version", mod.Version}}
+					if modJson, err1 := services.GetInstalledModJson(instance, mod); err1 == nil {
+						var depends []string
+						for dep, version := range modJson.Depends {
+							depends = append(depends, dep+" "+fmt.Sprint(version))
+						}
+						sort.Strings(depends)
+
+						installedDetails = append(installedDetails, []string{"Mod id", modJson.Id}, []string{"Environment", modJson.Environment}, []string{"Depends on", strings.Join(depends, ", ")})
+					}
+
+					dependents := services.GetModsRelyOn(&instance, mod.Slug)
+					installedDetails = append(installedDetails, []string{"Needed by", strings.Join(dependents, ", ")})
+					pterm.DefaultTable.WithData(installedDetails).Render()
+					fmt.Println()
+					return nil
+				},
+			},
+			{
+				Name:        "provide",
+				Usage:       "provide [--watch [folder]] [mod] [path to jar]",
+				Description: "Install a curseforge mod that has to be downloaded by hand",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "watch", Usage: "wait for the files to show up in a folder, defaults to your downloads folder"},
+				},
+				Action: func(c *cli.Context) error {
+					state := fileutils.LoadAppState()
+					instance, err := services.GetInstance(state.ActiveInstance)
+					if err != nil {
+						pterm.Error.Println("Must select an instance to modify ~ modman sel <name>")
+						return nil
+					}
+
+					if len(instance.ManualMods) == 0 {
+						pterm.Info.Println("No mods need a manual download")
+						return nil
+					}
+
+					if c.Bool("watch") {
+						dir := c.Args().Get(0)
+						if dir == "" {
+							dir = services.GetDownloadsFolder()
+						}
+
+						printManualDownloads(instance)
+						pterm.Info.Println("Watching " + dir + " ~ Ctrl+C to stop")
+						for len(instance.ManualMods) > 0 {
+							if len(services.ScanForManualMods(&instance, dir)) > 0 {
+								util.Fatal(services.SaveInstance(instance))
+							}
+							time.Sleep(2 * time.Second)
+						}
+						pterm.Success.Println("All manual downloads provided")
+						return nil
+					}
+
+					if c.Args().Len() < 2 {
+						printManualDownloads(instance)
+						return nil
+					}
+
+					mod, found := services.FindManualMod(instance, c.Args().Get(0))
+					if !found {
+						pterm.Error.Println(c.Args().Get(0) + " is not waiting for a manual download")
+						return nil
+					}
+
+					err1 := services.ProvideMod(&instance, mod, c.Args().Get(1))
+					if err1 != nil {
+						pterm.Error.Println("Could not use " + c.Args().Get(1) + ": " + err1.Error())
+						return nil
+					}
 					return services.SaveInstance(instance)
 				},
 			},
@@ -224,7 +729,7 @@ func main() {
 				Name:        "rm",
 				Aliases:     []string{"remove"},
 				Usage:       "rm [mod slug 1] [mod slug 2] [mod slug 3]",
-				Description: "Remove mods - as many as you like. Do not use c:",
+				Description: "Remove mods and packs - as many as you like. Packs other instances in .minecraft still use are kept. Do not use c:",
 				Action: func(c *cli.Context) error {
 					args := c.Args()
 					state := fileutils.LoadAppState()
@@ -287,14 +792,18 @@ func main() {
 
 					fmt.Println()
 					var mods [][]string
-					mods = append(mods, []string{"Name", "Version", "Filename"})
+					mods = append(mods, []string{"Name", "Type", "Version", "Filename"})
 
 					sort.Slice(instance.Mods, func(i, j int) bool {
 						return instance.Mods[i].Name < instance.Mods[j].Name
 					})
 
 					for _, mod := range instance.Mods {
-						mods = append(mods, []string{mod.Name, mod.Version, mod.Filename})
+						packType := mod.Type
+						if packType == "" {
+							packType = "mod"
+						}
+						mods = append(mods, []string{mod.Name, packType, mod.Version, mod.Filename})
 					}
 					pterm.DefaultTable.WithHasHeader().WithData(mods).Render()
 					fmt.Println()
@@ -315,16 +824,22 @@ func main() {
 					}
 
 					pterm.Info.Println("Updating " + instance.Name)
-					services.UpdateInstance(&state, instance.Name)
-					fileutils.SaveAppState(state)
+					services.UpdateInstance(instance.Name)
+					instance, _ = services.GetInstance(instance.Name)
+					printManualDownloads(instance)
 					pterm.Success.Println("Update complete")
 					return nil
 				},
 			},
 			{
 				Name:        "migrate",
-				Usage:       "migrate [mc version]",
-				Description: "migrates the selected instance to the inputed game version",
+				Usage:       "migrate [--check] [--in-place] [--loader quilt] [mc version]",
+				Description: "migrates the selected instance to the inputed game version, and optionally to another loader",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "loader", Usage: "loader to migrate to, defaults to the current one"},
+					&cli.BoolFlag{Name: "check", Usage: "only show which mods have a version for the game version"},
+					&cli.BoolFlag{Name: "in-place", Usage: "migrate the selected instance itself after saving a snapshot, mods without a release for the version are kept"},
+				},
 				Action: func(c *cli.Context) error {
 					state := fileutils.LoadAppState()
 					version := c.Args().Get(0)
@@ -340,31 +855,28 @@ func main() {
 						return nil
 					}
 
-					if !api.IsFabricVersionSupported(version) {
-						pterm.Error.Println("Version not supported ~ Lowest supported is 18w43b (1.14)")
-						return nil
+					loader := c.String("loader")
+					if loader == "" {
+						loader = oldInstance.Loader
 					}
 
-					pterm.Info.Println("Migrating " + state.ActiveInstance + " to " + version)
-					newName := state.ActiveInstance + "_Migrated"
-					err1 := services.CreateInstance(newName, oldInstance.Loader, version)
-					if err1 != nil {
-						newName = oldInstance.Name + "_Migrated:" + strings.ReplaceAll(time.Now().Format(time.RFC822), " ", "_")
-						services.CreateInstance(newName, oldInstance.Loader, version)
+					if !checkVersion(loader, version) {
+						return nil
 					}
 
-					newInstance, _ := services.GetInstance(newName)
+					if c.Bool("check") {
+						pterm.Info.Println("Checking " + oldInstance.Name + " against " + loader + " " + version)
+						printMigrationReport(services.CheckMigration(oldInstance, loader, version))
+						return nil
+					}
 
-					for _, mod := range oldInstance.Mods {
-						if len(services.GetModsRelyOn(&oldInstance, mod.Slug)) == 0 {
-							err2 := services.AddMod(&newInstance, mod.ProjectId, util.ModData{}, false)
-							if err2 != nil && err2.Error() == "failed to find matching version" {
-								pterm.Error.Println(mod.Name + " does not have a version for " + version)
-							}
-						}
+					pterm.Info.Println("Migrating " + oldInstance.Name + " to " + loader + " " + version)
+					newName, err1 := services.MigrateInstance(oldInstance.Name, loader, version, c.Bool("in-place"))
+					if err1 != nil {
+						pterm.Error.Println("Migration failed: " + err1.Error())
+						return nil
 					}
 
-					util.Fatal(services.SaveInstance(newInstance))
 					services.SetActiveInstance(newName)
 					pterm.Success.Println("Migration Complete")
 					return nil
@@ -382,26 +894,384 @@ func main() {
 						return nil
 					}
 
-					oldName := instance.Name
-					instance.Name = c.Args().Get(0)
+					newName := c.Args().Get(0)
+					if newName == "" {
+						pterm.Error.Println("Please enter a new name")
+						return nil
+					}
 
-					for i, in := range state.Instances {
-						if in.Name == oldName {
-							state.Instances[i] = instance
-							break
+					err1 := services.RenameInstance(instance.Name, newName)
+					if err1 != nil {
+						if err1.Error() == "already instance with that name" {
+							pterm.Error.Println("Instance with that name already exists")
+						} else {
+							pterm.Error.Println("Failed to rename " + instance.Name + ": " + err1.Error())
 						}
+						return nil
 					}
 
-					fileutils.SaveAppState(state)
-					services.SetActiveInstance(instance.Name)
-					pterm.Success.Println("Renamed " + oldName + " to " + instance.Name)
+					pterm.Success.Println("Renamed " + instance.Name + " to " + newName)
 					return nil
 				},
 			},
+			{
+				Name:        "isolate",
+				Usage:       "isolate [--copy]",
+				Description: "Moves the selected instance into a game folder of its own with its own mods, configs and saves",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "copy", Usage: "start from a copy of the configs, saves and options in .minecraft"},
+				},
+				Action: func(c *cli.Context) error {
+					state := fileutils.LoadAppState()
+					instance, err := services.GetInstance(state.ActiveInstance)
+					if err != nil {
+						pterm.Error.Println("Must select an instance to modify ~ modman sel <name>")
+						return nil
+					}
+
+					err1 := services.IsolateInstance(instance.Name, c.Bool("copy"))
+					if err1 != nil {
+						if err1.Error() == "instance already isolated" {
+							pterm.Info.Println(instance.Name + " is already isolated")
+						} else if err1.Error() == "server instances are always isolated" {
+							pterm.Info.Println(instance.Name + " is a server, it already has a folder of its own")
+						} else {
+							pterm.Error.Println("Failed to isolate " + instance.Name + ": " + err1.Error())
+						}
+						return nil
+					}
+
+					pterm.Success.Println(instance.Name + " now runs in " + instance.Path)
+					return nil
+				},
+			},
+			{
+				Name:        "clone",
+				Usage:       "clone [instance name] [new name]",
+				Description: "Copies an instance at the same version",
+				Action: func(c *cli.Context) error {
+					src := c.Args().Get(0)
+					dst := c.Args().Get(1)
+
+					if dst == "" {
+						pterm.Error.Println("Please enter a name for the copy")
+						return nil
+					}
+
+					if _, err := services.GetInstance(src); err != nil {
+						pterm.Error.Println("No instance with that name")
+						return nil
+					}
+
+					pterm.Info.Println("Cloning " + src + " to " + dst)
+					err1 := services.CloneInstance(src, dst)
+					if err1 != nil {
+						switch err1.Error() {
+						case "already instance with that name":
+							pterm.Error.Println("Instance with that name already exists")
+						case "invalid instance name":
+							pterm.Error.Println("Instance names can not be empty, . or .. or contain / or \\")
+						case "instance folder already exists":
+							pterm.Error.Println("The folder instances/" + dst + " already exists ~ Move it or pick another name")
+						default:
+							pterm.Error.Println(err1)
+						}
+						return nil
+					}
+
+					pterm.Success.Println("Cloned " + src + " to " + dst)
+					services.SetActiveInstance(dst)
+					return nil
+				},
+			},
+			{
+				Name:        "diff",
+				Usage:       "diff [--json] [instance | instance.json | instance.lock | pack.mrpack] [instance | instance.json | instance.lock | pack.mrpack]",
+				Description: "Shows the differences between two instances, exports, lockfiles or modpacks. Mods whose installed file does not match a lockfile show up as a different file",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "json", Usage: "print the differences as json"},
+				},
+				Action: func(c *cli.Context) error {
+					a, aConfigs, err := services.LoadDiffSource(c.Args().Get(0))
+					if err != nil {
+						pterm.Error.Println("Failed to read " + c.Args().Get(0))
+						return nil
+					}
+
+					b, bConfigs, err1 := services.LoadDiffSource(c.Args().Get(1))
+					if err1 != nil {
+						pterm.Error.Println("Failed to read " + c.Args().Get(1))
+						return nil
+					}
+
+					diff := services.DiffInstances(a, aConfigs, b, bConfigs)
+					if c.Bool("json") {
+						data, err2 := json.MarshalIndent(diff, "", " ")
+						util.Fatal(err2)
+						fmt.Println(string(data))
+						return nil
+					}
+
+					if diff.GameVersion != nil {
+						pterm.Info.Println("Game version: " + diff.GameVersion[0] + " -> " + diff.GameVersion[1])
+					}
+					if diff.Loader != nil {
+						pterm.Info.Println("Loader: " + diff.Loader[0] + " -> " + diff.Loader[1])
+					}
+					if diff.LoaderVersion != nil {
+						pterm.Info.Println("Loader version: " + diff.LoaderVersion[0] + " -> " + diff.LoaderVersion[1])
+					}
+
+					var mods [][]string
+					mods = append(mods, []string{" ", "Name", "Version"})
+					for _, mod := range diff.Added {
+						mods = append(mods, []string{pterm.FgGreen.Sprint("+"), mod.Name, mod.Version})
+					}
+					for _, mod := range diff.Removed {
+						mods = append(mods, []string{pterm.FgRed.Sprint("-"), mod.Name, mod.Version})
+					}
+					for _, mod := range diff.Changed {
+						mods = append(mods, []string{pterm.FgYellow.Sprint("~"), mod.Name, mod.OldVersion + " -> " + mod.NewVersion})
+					}
+					for _, config := range diff.Configs {
+						mods = append(mods, []string{pterm.FgYellow.Sprint("~"), "config/" + config.File, config.Change})
+					}
+
+					if len(mods) == 1 && diff.GameVersion == nil && diff.Loader == nil && diff.LoaderVersion == nil {
+						pterm.Success.Println("No differences")
+						return nil
+					}
+
+					if len(mods) > 1 {
+						fmt.Println()
+						pterm.DefaultTable.WithHasHeader().WithData(mods).Render()
+						fmt.Println()
+					}
+					return nil
+				},
+			},
+			{
+				Name:        "config",
+				Usage:       "config [track | untrack | ls | diff | reset | set]",
+				Description: "Manage the config files of the selected instance, tracked configs are exported with it and kept through migrations",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "track",
+						Usage:       "config track [--template] [config file 1] [config file 2]",
+						Description: "Track config files of an isolated or server instance, or make their current content the new default when they are tracked already",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "template", Usage: "render the file with text/template, ex: {{.Name}}, {{.Version}} or {{.Vars.server}}"},
+						},
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance to modify ~ modman sel <name>")
+								return nil
+							}
+
+							for _, file := range c.Args().Slice() {
+								path, err1 := services.TrackConfig(&instance, file, c.Bool("template"))
+								if err1 != nil {
+									if err1.Error() == "shared config folder" {
+										pterm.Error.Println("Could not track " + file + ": " + instance.Name + " shares .minecraft/config with other instances ~ modman isolate")
+										return nil
+									}
+									if err1.Error() == "config not found" || err1.Error() == "not in config folder" {
+										pterm.Error.Println("Could not track " + file + ": " + err1.Error())
+									} else {
+										pterm.Error.Println("Failed to render " + file + ": " + err1.Error())
+									}
+									continue
+								}
+								pterm.Success.Println("Tracking config/" + path)
+							}
+							util.Fatal(services.SaveInstance(instance))
+							return nil
+						},
+					},
+					{
+						Name:        "untrack",
+						Usage:       "config untrack [config file 1] [config file 2]",
+						Description: "Stop tracking config files, the files stay where they are",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance to modify ~ modman sel <name>")
+								return nil
+							}
+
+							for _, file := range c.Args().Slice() {
+								path, err1 := services.UntrackConfig(&instance, file)
+								if err1 != nil {
+									pterm.Error.Println(file + " is not tracked")
+									continue
+								}
+								pterm.Success.Println("Stopped tracking config/" + path)
+							}
+							util.Fatal(services.SaveInstance(instance))
+							return nil
+						},
+					},
+					{
+						Name:        "list",
+						Aliases:     []string{"ls"},
+						Usage:       "config list",
+						Description: "List the tracked config files and whether they were edited",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance ~ modman sel <name>")
+								return nil
+							}
+
+							statuses := services.GetConfigStatus(instance)
+							if len(statuses) == 0 {
+								pterm.Info.Println("No tracked configs ~ modman config track <file>")
+								return nil
+							}
+
+							fmt.Println()
+							var configs [][]string
+							configs = append(configs, []string{"File", "Template", "Status"})
+							for _, status := range statuses {
+								text := status.Status
+								switch status.Status {
+								case "edited":
+									text = pterm.FgYellow.Sprint(status.Status)
+								case "missing":
+									text = pterm.FgRed.Sprint(status.Status)
+								}
+								configs = append(configs, []string{"config/" + status.Path, fmt.Sprint(status.Template), text})
+							}
+							pterm.DefaultTable.WithHasHeader().WithData(configs).Render()
+							fmt.Println()
+							return nil
+						},
+					},
+					{
+						Name:        "diff",
+						Usage:       "config diff [config file 1] [config file 2]",
+						Description: "Show local edits against the defaults, of every tracked config when no file is given",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance ~ modman sel <name>")
+								return nil
+							}
+
+							files := c.Args().Slice()
+							if len(files) == 0 {
+								for _, status := range services.GetConfigStatus(instance) {
+									if status.Status == "edited" {
+										files = append(files, status.Path)
+									}
+								}
+							}
+
+							if len(files) == 0 {
+								pterm.Success.Println("No local edits")
+								return nil
+							}
+
+							for _, file := range files {
+								lines, err1 := services.DiffConfig(instance, file)
+								if err1 != nil {
+									if err1.Error() == "config not tracked" {
+										pterm.Error.Println(file + " is not tracked ~ modman config track " + file)
+									} else {
+										pterm.Error.Println("Could not diff " + file + ": " + err1.Error())
+									}
+									continue
+								}
+
+								pterm.DefaultSection.Println(file)
+								if len(lines) == 0 {
+									pterm.Info.Println("No local edits")
+									continue
+								}
+								for _, line := range lines {
+									switch {
+									case strings.HasPrefix(line, "@@"):
+										pterm.FgCyan.Println(line)
+									case strings.HasPrefix(line, "+"):
+										pterm.FgGreen.Println(line)
+									case strings.HasPrefix(line, "-"):
+										pterm.FgRed.Println(line)
+									default:
+										fmt.Println(line)
+									}
+								}
+							}
+							return nil
+						},
+					},
+					{
+						Name:        "reset",
+						Usage:       "config reset [config file 1] [config file 2]",
+						Description: "Throw away local edits, writing the defaults of tracked configs back",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance to modify ~ modman sel <name>")
+								return nil
+							}
+
+							for _, file := range c.Args().Slice() {
+								path, err1 := services.ResetConfig(instance, file)
+								if err1 != nil {
+									if err1.Error() == "config not tracked" {
+										pterm.Error.Println(file + " is not tracked")
+									} else {
+										pterm.Error.Println("Failed to reset " + file + ": " + err1.Error())
+									}
+									continue
+								}
+								pterm.Success.Println("Reset config/" + path)
+							}
+							return nil
+						},
+					},
+					{
+						Name:        "set",
+						Usage:       "config set [name] [value]",
+						Description: "Set a value config templates can use as {{.Vars.name}}, leave the value out to remove it",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance to modify ~ modman sel <name>")
+								return nil
+							}
+
+							name := c.Args().Get(0)
+							if name == "" {
+								pterm.Error.Println("Please enter a name")
+								return nil
+							}
+
+							for _, config := range services.SetConfigVar(&instance, name, c.Args().Get(1)) {
+								pterm.Warning.Println("Kept your edits to config/" + config + " ~ modman config reset " + config)
+							}
+							util.Fatal(services.SaveInstance(instance))
+							if c.Args().Get(1) == "" {
+								pterm.Success.Println("Removed " + name + " from " + instance.Name)
+							} else {
+								pterm.Success.Println("Set " + name + " for " + instance.Name)
+							}
+							return nil
+						},
+					},
+				},
+			},
 			{
 				Name:        "export",
-				Usage:       "export",
-				Description: "Exports the selected instance",
+				Usage:       "export [server] [dir]",
+				Description: "Exports the selected instance, or its server compatible mods and tracked configs into a folder",
 				Action: func(c *cli.Context) error {
 					state := fileutils.LoadAppState()
 					instance, err := services.GetInstance(state.ActiveInstance)
@@ -410,12 +1280,56 @@ func main() {
 						return nil
 					}
 
+					if c.Args().Get(0) == "server" {
+						dir := c.Args().Get(1)
+						if dir == "" {
+							pterm.Error.Println("Please enter a folder to export to")
+							return nil
+						}
+
+						pterm.Info.Println("Exporting server mods of " + instance.Name + " to " + dir)
+						skipped, skippedConfigs := services.ExportServerMods(instance, dir)
+						for _, mod := range skipped {
+							pterm.Info.Println("Skipped client only mod " + mod.Name)
+						}
+						for _, config := range skippedConfigs {
+							pterm.Warning.Println("Skipped config/" + config + ", its template could not be rendered ~ modman config set <name> <value>")
+						}
+						pterm.Success.Println("Exported server mods of " + instance.Name)
+						return nil
+					}
+
 					pterm.Info.Println("Exporting " + instance.Name)
 					services.ExportInstance(instance)
 					pterm.Success.Println("Exported " + instance.Name)
 					return nil
 				},
 			},
+			{
+				Name:        "lock",
+				Usage:       "lock [file]",
+				Description: "Writes the versions and file hashes of everything the selected instance has installed into a lockfile, exports/<name>.lock by default",
+				Action: func(c *cli.Context) error {
+					state := fileutils.LoadAppState()
+					instance, err := services.GetInstance(state.ActiveInstance)
+					if err != nil {
+						pterm.Error.Println("Must select an instance ~ modman sel <name>")
+						return nil
+					}
+
+					file := c.Args().Get(0)
+					if file == "" {
+						file = state.WorkDir + "/exports/" + instance.Name + ".lock"
+					}
+
+					if err1 := services.WriteLockfile(instance, file); err1 != nil {
+						pterm.Error.Println("Failed to write " + file + ": " + err1.Error())
+						return nil
+					}
+					pterm.Success.Println("Locked " + instance.Name + " to " + file + " ~ modman diff " + instance.Name + " " + file)
+					return nil
+				},
+			},
 			{
 				Name:        "import",
 				Usage:       "import [instance | mods] [instance.json]",
@@ -426,12 +1340,24 @@ func main() {
 
 					if method == "instance" {
 						pterm.Info.Println("Importing " + file)
-						name := services.ImportInstance(file)
+						name, err := services.ImportInstance(file)
+						if err != nil {
+							if err.Error() == "already instance with that name" {
+								pterm.Error.Println("Instance with that name already exists")
+							} else if err.Error() == "shared config folder" {
+								pterm.Error.Println("Could not import " + file + ": it tracks configs but is neither isolated nor a server")
+							} else if err.Error() == "not in config folder" {
+								pterm.Error.Println("Could not import " + file + ": it has a config outside of the config folder")
+							} else {
+								pterm.Error.Println("Failed to import " + file + ": " + err.Error())
+							}
+							return nil
+						}
 						pterm.Success.Println("Imported " + name)
 					}
 
 					if method == "mods" {
-						pterm.Info.Println("Importing mods from" + file)
+						pterm.Info.Println("Importing mods from " + file)
 
 						state := fileutils.LoadAppState()
 						instance, err := services.GetInstance(state.ActiveInstance)
@@ -440,13 +1366,289 @@ func main() {
 							return nil
 						}
 
-						services.ImportMods(&instance, file)
+						for _, jar := range services.ImportMods(&instance, file) {
+							pterm.Warning.Println("Could not identify " + jar)
+						}
+						util.Fatal(services.SaveInstance(instance))
 						pterm.Success.Println("Imported mods from " + file)
 					}
 
 					return nil
 				},
 			},
+			{
+				Name:        "loader",
+				Usage:       "loader [list | set | update]",
+				Description: "Manage the loader version of the selected instance",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "list",
+						Aliases:     []string{"ls"},
+						Usage:       "loader list [--all]",
+						Description: "List available loader versions",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "all", Usage: "include unstable versions"},
+						},
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance ~ modman sel <name>")
+								return nil
+							}
+
+							versions, err1 := api.GetLoaderVersions(instance.Loader)
+							if err1 != nil {
+								pterm.Error.Println("Unknown loader " + instance.Loader)
+								return nil
+							}
+
+							fmt.Println()
+							var loaders [][]string
+							loaders = append(loaders, []string{" ", "Version", "Stable"})
+							for _, version := range versions {
+								if !version.Stable && !c.Bool("all") && version.Version != instance.LoaderVersion {
+									continue
+								}
+
+								var prefix string
+								if version.Version == instance.LoaderVersion {
+									prefix = ">"
+								}
+								loaders = append(loaders, []string{prefix, version.Version, fmt.Sprint(version.Stable)})
+							}
+							pterm.DefaultTable.WithHasHeader().WithData(loaders).Render()
+							fmt.Println()
+
+							if instance.LoaderPinned {
+								pterm.Info.Println(instance.Name + " is pinned to " + instance.LoaderVersion + " ~ modman loader update")
+							}
+							return nil
+						},
+					},
+					{
+						Name:        "set",
+						Usage:       "loader set [loader version]",
+						Description: "Pin the selected instance to a loader version",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance to modify ~ modman sel <name>")
+								return nil
+							}
+
+							err1 := services.SetLoaderVersion(&instance, c.Args().Get(0))
+							if err1 != nil {
+								if err1.Error() == "unknown loader version" {
+									pterm.Error.Println(c.Args().Get(0) + " is not a " + instance.Loader + " loader version ~ modman loader list --all")
+								} else {
+									pterm.Error.Println(err1)
+								}
+								return nil
+							}
+
+							instance.LoaderPinned = true
+							util.Fatal(services.SaveInstance(instance))
+							pterm.Success.Println("Pinned " + instance.Name + " to " + instance.Loader + " loader " + instance.LoaderVersion)
+							return nil
+						},
+					},
+					{
+						Name:        "update",
+						Usage:       "loader update",
+						Description: "Unpin and update the loader of the selected instance to the latest stable version",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance to update ~ modman sel <name>")
+								return nil
+							}
+
+							lversion, err1 := services.GetLatestLoaderVersion(instance.Loader)
+							if err1 != nil {
+								pterm.Error.Println(err1)
+								return nil
+							}
+
+							instance.LoaderPinned = false
+							if services.IsNewerVersion(instance.LoaderVersion, lversion) {
+								util.Fatal(services.SetLoaderVersion(&instance, lversion))
+							}
+
+							util.Fatal(services.SaveInstance(instance))
+							pterm.Success.Println(instance.Name + " is on " + instance.Loader + " loader " + instance.LoaderVersion)
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:        "launch",
+				Usage:       "launch [--print] [--username name]",
+				Description: "Starts the selected instance without the minecraft launcher",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "print", Usage: "only print the launch command"},
+					&cli.StringFlag{Name: "username", Value: "Player", Usage: "offline username to play as"},
+				},
+				Action: func(c *cli.Context) error {
+					state := fileutils.LoadAppState()
+					instance, err := services.GetInstance(state.ActiveInstance)
+					if err != nil {
+						pterm.Error.Println("Must select an instance to launch ~ modman sel <name>")
+						return nil
+					}
+
+					if !c.Bool("print") {
+						pterm.Info.Println("Preparing " + instance.Name)
+					}
+
+					command, err1 := services.GetLaunchCommand(instance, c.String("username"))
+					if err1 != nil {
+						pterm.Error.Println(err1)
+						return nil
+					}
+
+					if c.Bool("print") {
+						for i, arg := range command {
+							if strings.ContainsAny(arg, " \"'") {
+								command[i] = "\"" + strings.ReplaceAll(arg, "\"", "\\\"") + "\""
+							}
+						}
+						fmt.Println(strings.Join(command, " "))
+						return nil
+					}
+
+					pterm.Info.Println("Launching " + instance.Name + " as " + c.String("username"))
+					cmd := exec.Command(command[0], command[1:]...)
+					cmd.Dir = services.GetGameDir(instance)
+					cmd.Stdin = os.Stdin
+					cmd.Stdout = os.Stdout
+					cmd.Stderr = os.Stderr
+					return cmd.Run()
+				},
+			},
+			{
+				Name:        "java",
+				Usage:       "java [list | set]",
+				Description: "Manage the java runtime and jvm settings of the selected instance",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "list",
+						Aliases:     []string{"ls"},
+						Usage:       "java list",
+						Description: "List java runtimes found on this system",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, _ := services.GetInstance(state.ActiveInstance)
+
+							var required int
+							if instance.Version != "" {
+								required, _ = api.GetJavaVersion(instance.Version)
+								pterm.Info.Println(instance.Name + " needs java " + fmt.Sprint(required))
+							}
+
+							installs := services.FindJavaInstalls()
+							if len(installs) == 0 {
+								pterm.Warning.Println("No java runtimes found")
+								return nil
+							}
+
+							fmt.Println()
+							var javas [][]string
+							javas = append(javas, []string{" ", "Version", "Path"})
+							for _, java := range installs {
+								var prefix string
+								if java.Version == required {
+									prefix = ">"
+								}
+								javas = append(javas, []string{prefix, fmt.Sprint(java.Version), java.Path})
+							}
+							pterm.DefaultTable.WithHasHeader().WithData(javas).Render()
+							fmt.Println()
+							return nil
+						},
+					},
+					{
+						Name:        "set",
+						Usage:       "java set [--memory 4G] [--args \"-XX:+UseZGC\"] [--path /usr/bin/java]",
+						Description: "Set the memory, jvm arguments and java runtime of the selected instance",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "memory", Usage: "max memory, ex: 4G"},
+							&cli.StringFlag{Name: "args", Usage: "extra jvm arguments, replaces the default G1GC flags"},
+							&cli.StringFlag{Name: "path", Usage: "path to the java executable"},
+						},
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							instance, err := services.GetInstance(state.ActiveInstance)
+							if err != nil {
+								pterm.Error.Println("Must select an instance to modify ~ modman sel <name>")
+								return nil
+							}
+
+							if c.String("path") != "" {
+								if _, err1 := os.Stat(c.String("path")); os.IsNotExist(err1) {
+									pterm.Error.Println("No java found at " + c.String("path"))
+									return nil
+								}
+							}
+
+							services.SetJavaSettings(&instance, c.String("memory"), c.String("args"), c.String("path"))
+							util.Fatal(services.SaveInstance(instance))
+							pterm.Success.Println("Updated java settings of " + instance.Name)
+							checkJava(instance)
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:        "settings",
+				Usage:       "settings [list | set]",
+				Description: "View and change modman settings",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "list",
+						Aliases:     []string{"ls"},
+						Usage:       "settings list",
+						Description: "List all settings",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+
+							fmt.Println()
+							settings := [][]string{{"Setting", "Value"}}
+							settings = append(settings, services.GetSettings(state.Config)...)
+							pterm.DefaultTable.WithHasHeader().WithData(settings).Render()
+							fmt.Println()
+							return nil
+						},
+					},
+					{
+						Name:        "set",
+						Usage:       "settings set [setting] [value]",
+						Description: "Change a setting, lists are comma separated. An empty value puts optional numbers like HttpRetries back to their default",
+						Action: func(c *cli.Context) error {
+							state := fileutils.LoadAppState()
+							key := c.Args().Get(0)
+
+							err := services.SetSetting(&state.Config, key, c.Args().Get(1))
+							if err != nil {
+								if err.Error() == "unknown setting" {
+									pterm.Error.Println("Unknown setting " + key + " ~ modman settings list")
+								} else {
+									pterm.Error.Println("Invalid value for " + key + ": " + err.Error())
+								}
+								return nil
+							}
+
+							fileutils.SaveAppState(state)
+							pterm.Success.Println("Updated " + key)
+							return nil
+						},
+					},
+				},
+			},
 			{
 				Name:        "v",
 				Aliases:     []string{"version"},
@@ -459,5 +1661,8 @@ func main() {
 			},
 		},
 	}
-	util.Fatal(app.Run(os.Args))
+}
+
+func main() {
+	util.Fatal(newApp().Run(os.Args))
 }
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/mrnavastar/modman/util"
+)
+
+// configContext is how many unchanged lines are shown around the changes of a config diff
+const configContext = 2
+
+// configTemplateData is what config templates can use, ex: {{.Name}} or {{.Vars.server}}
+type configTemplateData struct {
+	Name          string
+	Version       string
+	Loader        string
+	LoaderVersion string
+	Server        bool
+	Vars          map[string]string
+}
+
+type ConfigStatus struct {
+	Path     string
+	Template bool
+	Status   string
+}
+
+// GetConfigFolder returns the config folder of the game an instance runs in
+func GetConfigFolder(instance util.Instance) string {
+	return GetGameDir(instance) + "/config"
+}
+
+// normalizeConfigPath turns a file given as config/name, name or a full path into its path relative to the config folder
+func normalizeConfigPath(instance util.Instance, file string) (p string, e error) {
+	if filepath.IsAbs(file) {
+		relative, err := filepath.Rel(GetConfigFolder(instance), file)
+		if err != nil {
+			return "", errors.New("not in config folder")
+		}
+		file = relative
+	}
+
+	file = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(file)), "config/")
+	if file == "." || file == ".." || strings.HasPrefix(file, "../") {
+		return "", errors.New("not in config folder")
+	}
+	return file, nil
+}
+
+// normalizeConfigs checks the paths of configs that come from a file, they have to stay in the config folder
+func normalizeConfigs(instance util.Instance, configs []util.ConfigFile) (c []util.ConfigFile, e error) {
+	var normalized []util.ConfigFile
+	for _, config := range configs {
+		if filepath.IsAbs(config.Path) {
+			return nil, errors.New("not in config folder")
+		}
+
+		path, err := normalizeConfigPath(instance, config.Path)
+		if err != nil {
+			return nil, err
+		}
+		config.Path = path
+		normalized = append(normalized, config)
+	}
+	return normalized, nil
+}
+
+// configFile returns where a tracked config goes in folder, paths that would leave it are an error
+func configFile(folder string, path string) (f string, e error) {
+	if filepath.IsAbs(path) {
+		return "", errors.New("not in config folder")
+	}
+
+	clean, err := normalizeConfigPath(util.Instance{}, path)
+	if err != nil {
+		return "", err
+	}
+	return folder + "/" + clean, nil
+}
+
+func findConfig(instance util.Instance, path string) (c util.ConfigFile, found bool) {
+	for _, config := range instance.Configs {
+		if config.Path == path {
+			return config, true
+		}
+	}
+	return util.ConfigFile{}, false
+}
+
+// renderConfig returns the default content of a tracked config, filling in templates with the values of the instance
+func renderConfig(instance util.Instance, config util.ConfigFile) (s string, e error) {
+	if !config.Template {
+		return config.Content, nil
+	}
+
+	tmpl, err := template.New(config.Path).Option("missingkey=error").Parse(config.Content)
+	if err != nil {
+		return "", err
+	}
+
+	vars := instance.ConfigVars
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	var out bytes.Buffer
+	err1 := tmpl.Execute(&out, configTemplateData{
+		Name:          instance.Name,
+		Version:       instance.Version,
+		Loader:        instance.Loader,
+		LoaderVersion: instance.LoaderVersion,
+		Server:        instance.Server,
+		Vars:          vars,
+	})
+	return out.String(), err1
+}
+
+func writeConfig(instance util.Instance, path string, content string) error {
+	file, err0 := configFile(GetConfigFolder(instance), path)
+	if err0 != nil {
+		return err0
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, []byte(content), 0644)
+}
+
+// TrackConfig Must call SaveInstance after using! - makes the current content of a config file its default, tracking it
+// when it is new. Template configs keep the file as their template and are rendered back into it. Only isolated and
+// server instances have a config folder of their own, the shared one would be tracked by every instance on it
+func TrackConfig(instance *util.Instance, file string, isTemplate bool) (p string, e error) {
+	if !instance.Isolated && !instance.Server {
+		return "", errors.New("shared config folder")
+	}
+
+	path, err := normalizeConfigPath(*instance, file)
+	if err != nil {
+		return "", err
+	}
+
+	data, err1 := ioutil.ReadFile(GetConfigFolder(*instance) + "/" + path)
+	if err1 != nil {
+		return "", errors.New("config not found")
+	}
+
+	config := util.ConfigFile{Path: path, Content: string(data), Template: isTemplate}
+	if isTemplate {
+		rendered, err2 := renderConfig(*instance, config)
+		if err2 != nil {
+			return "", err2
+		}
+
+		if err3 := writeConfig(*instance, path, rendered); err3 != nil {
+			return "", err3
+		}
+	}
+
+	for i, tracked := range instance.Configs {
+		if tracked.Path == path {
+			instance.Configs[i] = config
+			return path, nil
+		}
+	}
+	instance.Configs = append(instance.Configs, config)
+	sort.Slice(instance.Configs, func(i, j int) bool {
+		return instance.Configs[i].Path < instance.Configs[j].Path
+	})
+	return path, nil
+}
+
+// UntrackConfig Must call SaveInstance after using! - stops tracking a config, the file itself is left alone
+func UntrackConfig(instance *util.Instance, file string) (p string, e error) {
+	path, err := normalizeConfigPath(*instance, file)
+	if err != nil {
+		return "", err
+	}
+
+	for i, config := range instance.Configs {
+		if config.Path == path {
+			instance.Configs = append(instance.Configs[:i], instance.Configs[i+1:]...)
+			return path, nil
+		}
+	}
+	return "", errors.New("config not tracked")
+}
+
+// GetConfigStatus compares every tracked config with its file. Status is one of unchanged, edited or missing
+func GetConfigStatus(instance util.Instance) []ConfigStatus {
+	var statuses []ConfigStatus
+	for _, config := range instance.Configs {
+		status := ConfigStatus{Path: config.Path, Template: config.Template, Status: "unchanged"}
+
+		rendered, err := renderConfig(instance, config)
+		data, err1 := ioutil.ReadFile(GetConfigFolder(instance) + "/" + config.Path)
+		if err1 != nil {
+			status.Status = "missing"
+		} else if err != nil || string(data) != rendered {
+			status.Status = "edited"
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// ResetConfig writes the default content of a tracked config over its file, undoing local edits
+func ResetConfig(instance util.Instance, file string) (p string, e error) {
+	path, err := normalizeConfigPath(instance, file)
+	if err != nil {
+		return "", err
+	}
+
+	config, found := findConfig(instance, path)
+	if !found {
+		return "", errors.New("config not tracked")
+	}
+
+	rendered, err1 := renderConfig(instance, config)
+	if err1 != nil {
+		return "", err1
+	}
+	return path, writeConfig(instance, path, rendered)
+}
+
+// writeConfigs writes the default of every tracked config that has no file yet. Files that are already there are kept,
+// the ones that differ from their default are returned
+func writeConfigs(instance util.Instance) []string {
+	var kept []string
+	for _, config := range instance.Configs {
+		rendered, err := renderConfig(instance, config)
+		if err != nil {
+			kept = append(kept, config.Path)
+			continue
+		}
+
+		data, err1 := ioutil.ReadFile(GetConfigFolder(instance) + "/" + config.Path)
+		if err1 != nil {
+			util.Fatal(writeConfig(instance, config.Path, rendered))
+			continue
+		}
+
+		if string(data) != rendered {
+			kept = append(kept, config.Path)
+		}
+	}
+	return kept
+}
+
+// exportConfigs writes the default of every tracked config into dir as a server would use it, templates that fail to render are returned
+func exportConfigs(instance util.Instance, dir string) []string {
+	server := instance
+	server.Server = true
+
+	var skipped []string
+	for _, config := range instance.Configs {
+		rendered, err := renderConfig(server, config)
+		if err != nil {
+			skipped = append(skipped, config.Path)
+			continue
+		}
+
+		file, err1 := configFile(dir, config.Path)
+		if err1 != nil {
+			skipped = append(skipped, config.Path)
+			continue
+		}
+		util.Fatal(os.MkdirAll(filepath.Dir(file), 0700))
+		util.Fatal(ioutil.WriteFile(file, []byte(rendered), 0644))
+	}
+	return skipped
+}
+
+// copyConfigs copies the tracked config files of one instance into the config folder of another, keeping local edits
+// when an instance moves to a new game folder. Nothing happens when both use the same folder
+func copyConfigs(from util.Instance, to util.Instance) error {
+	if GetConfigFolder(from) == GetConfigFolder(to) {
+		return nil
+	}
+
+	for _, config := range from.Configs {
+		data, err := ioutil.ReadFile(GetConfigFolder(from) + "/" + config.Path)
+		if err != nil {
+			continue
+		}
+
+		if err1 := writeConfig(to, config.Path, string(data)); err1 != nil {
+			return err1
+		}
+	}
+	return nil
+}
+
+// rerenderConfigs writes the templates of an instance again after its values changed from old. Templates whose file
+// no longer matches what old rendered were edited, they are left alone and returned
+func rerenderConfigs(old util.Instance, instance util.Instance) []string {
+	var kept []string
+	for _, config := range instance.Configs {
+		if !config.Template {
+			continue
+		}
+
+		before, err := renderConfig(old, config)
+		data, err1 := ioutil.ReadFile(GetConfigFolder(instance) + "/" + config.Path)
+		if err == nil && err1 == nil && string(data) != before {
+			kept = append(kept, config.Path)
+			continue
+		}
+
+		rendered, err2 := renderConfig(instance, config)
+		if err2 != nil {
+			kept = append(kept, config.Path)
+			continue
+		}
+		util.Fatal(writeConfig(instance, config.Path, rendered))
+	}
+	return kept
+}
+
+// SetConfigVar Must call SaveInstance after using! - sets a value for config templates, an empty value removes it.
+// The templates are rendered again, the ones whose file was edited are returned
+func SetConfigVar(instance *util.Instance, name string, value string) []string {
+	old := *instance
+	vars := map[string]string{}
+	for key, v := range instance.ConfigVars {
+		vars[key] = v
+	}
+
+	if value == "" {
+		delete(vars, name)
+	} else {
+		vars[name] = value
+	}
+	instance.ConfigVars = vars
+	return rerenderConfigs(old, *instance)
+}
+
+// diffLines returns the changes going from a to b as lines starting with a space, - or +, using their longest common subsequence
+func diffLines(a []string, b []string) []string {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lengths[i+1][j] >= lengths[i][j+1]):
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	return lines
+}
+
+// DiffConfig shows the local edits of a tracked config against its default. Unchanged lines far from a change are left out,
+// a line starting with @@ tells which line of the file the next change is at
+func DiffConfig(instance util.Instance, file string) (d []string, e error) {
+	path, err := normalizeConfigPath(instance, file)
+	if err != nil {
+		return nil, err
+	}
+
+	config, found := findConfig(instance, path)
+	if !found {
+		return nil, errors.New("config not tracked")
+	}
+
+	rendered, err1 := renderConfig(instance, config)
+	if err1 != nil {
+		return nil, err1
+	}
+
+	data, err2 := ioutil.ReadFile(GetConfigFolder(instance) + "/" + path)
+	if err2 != nil {
+		return nil, errors.New("config not found")
+	}
+
+	lines := diffLines(strings.Split(rendered, "\n"), strings.Split(string(data), "\n"))
+	var shown []string
+	skipped := true
+	line := 1
+	for i, diffLine := range lines {
+		near := false
+		for j := i - configContext; j <= i+configContext; j++ {
+			if j >= 0 && j < len(lines) && lines[j][0] != ' ' {
+				near = true
+			}
+		}
+
+		if near {
+			if skipped {
+				shown = append(shown, fmt.Sprintf("@@ line %d", line))
+			}
+			shown = append(shown, diffLine)
+		}
+		skipped = !near
+
+		if diffLine[0] != '-' {
+			line++
+		}
+	}
+	return shown, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mrnavastar/modman/util"
+)
+
+func TestNormalizeConfigPath(t *testing.T) {
+	instance := util.Instance{Name: "pack", Path: filepath.Join(t.TempDir(), "pack"), Isolated: true}
+	for file, expected := range map[string]string{
+		"sodium-options.json":                                            "sodium-options.json",
+		"config/sodium-options.json":                                     "sodium-options.json",
+		"./config/iris/shaders.json":                                     "iris/shaders.json",
+		"iris/../sodium-options.json":                                    "sodium-options.json",
+		filepath.Join(GetConfigFolder(instance), "iris", "shaders.json"): "iris/shaders.json",
+	} {
+		if path, err := normalizeConfigPath(instance, file); err != nil || path != expected {
+			t.Errorf("expected %s for %s, got %s %v", expected, file, path, err)
+		}
+	}
+
+	for _, file := range []string{"", "..", "../options.txt", "config/../../options.txt", filepath.Join(instance.Path, "options.txt")} {
+		if path, err := normalizeConfigPath(instance, file); err == nil {
+			t.Errorf("expected %s to be outside the config folder, got %s", file, path)
+		}
+	}
+}
+
+func TestNormalizeConfigs(t *testing.T) {
+	instance := util.Instance{Name: "pack", Path: filepath.Join(t.TempDir(), "pack"), Isolated: true}
+	configs, err := normalizeConfigs(instance, []util.ConfigFile{{Path: "config/sodium-options.json"}, {Path: "iris/../server.toml"}})
+	if err != nil || len(configs) != 2 || configs[0].Path != "sodium-options.json" || configs[1].Path != "server.toml" {
+		t.Errorf("unexpected configs %+v %v", configs, err)
+	}
+
+	// configs from a file never leave the config folder, not even with a full path into it
+	for _, path := range []string{"../options.txt", filepath.Join(GetConfigFolder(instance), "sodium-options.json")} {
+		if _, err1 := normalizeConfigs(instance, []util.ConfigFile{{Path: "server.toml"}, {Path: path}}); err1 == nil {
+			t.Errorf("expected %s to be rejected", path)
+		}
+	}
+}
+
+func TestConfigFolderEscape(t *testing.T) {
+	instance := util.Instance{Name: "pack", Path: filepath.Join(t.TempDir(), "pack"), Isolated: true}
+	if err := writeConfig(instance, "../options.txt", "fov:1.0"); err == nil {
+		t.Error("expected a write outside of the config folder to fail")
+	}
+
+	dir := filepath.Join(t.TempDir(), "config")
+	instance.Configs = []util.ConfigFile{{Path: "../options.txt"}, {Path: "sodium-options.json", Content: "{}"}}
+	if skipped := exportConfigs(instance, dir); !reflect.DeepEqual(skipped, []string{"../options.txt"}) {
+		t.Errorf("expected the config outside of the folder to be skipped, got %q", skipped)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "..", "options.txt")); err == nil {
+		t.Error("expected nothing to be written outside of the export")
+	}
+}
+
+func TestTrackSharedConfig(t *testing.T) {
+	instance := util.Instance{Name: "shared", Path: filepath.Join(t.TempDir(), "shared")}
+	if _, err := TrackConfig(&instance, "sodium-options.json", false); err == nil || err.Error() != "shared config folder" {
+		t.Errorf("expected an instance on the shared config folder to not track configs, got %v", err)
+	}
+}
+
+func TestRenderConfig(t *testing.T) {
+	instance := util.Instance{Name: "pack", Version: "1.19.2", Loader: "fabric", ConfigVars: map[string]string{"server": "play.example.com"}}
+	rendered, err := renderConfig(instance, util.ConfigFile{Path: "server.toml", Content: "name = \"{{.Name}} {{.Version}}\"\nip = \"{{.Vars.server}}\"\n", Template: true})
+	if err != nil || rendered != "name = \"pack 1.19.2\"\nip = \"play.example.com\"\n" {
+		t.Errorf("unexpected render %q %v", rendered, err)
+	}
+
+	// plain configs are not templates, missing values are an error instead of an empty string
+	if plain, err1 := renderConfig(instance, util.ConfigFile{Content: "{{.Name}}"}); err1 != nil || plain != "{{.Name}}" {
+		t.Errorf("expected a plain config to be kept as is, got %q %v", plain, err1)
+	}
+	if _, err2 := renderConfig(instance, util.ConfigFile{Content: "{{.Vars.missing}}", Template: true}); err2 == nil {
+		t.Error("expected a missing value to fail")
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	lines := diffLines([]string{"{", "\"fps\": 60", "\"quality\": \"high\"", "}"}, []string{"{", "\"fps\": 144", "\"quality\": \"high\"", "\"vsync\": true", "}"})
+	expected := []string{" {", "-\"fps\": 60", "+\"fps\": 144", " \"quality\": \"high\"", "+\"vsync\": true", " }"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
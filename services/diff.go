@@ -0,0 +1,266 @@
+package services
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mrnavastar/modman/util"
+)
+
+type ModChange struct {
+	Name       string
+	OldVersion string
+	NewVersion string
+}
+
+type ConfigChange struct {
+	File   string
+	Change string
+}
+
+type InstanceDiff struct {
+	GameVersion   []string
+	Loader        []string
+	LoaderVersion []string
+	Added         []util.ModData
+	Removed       []util.ModData
+	Changed       []ModChange
+	Configs       []ConfigChange
+}
+
+type mrpackIndex struct {
+	Name         string
+	Dependencies map[string]string
+	Files        []struct {
+		Path      string
+		Downloads []string
+	}
+}
+
+var modrinthCdnRegex = regexp.MustCompile(`/data/([^/]+)/versions/([^/]+)/`)
+
+// hashConfigs returns the sha1 of every tracked config file of an instance that exists, keyed by its path in the config folder.
+// Untracked files are left out, exports do not know them
+func hashConfigs(instance util.Instance) map[string]string {
+	configs := map[string]string{}
+	for _, config := range instance.Configs {
+		if data, err := ioutil.ReadFile(GetConfigFolder(instance) + "/" + config.Path); err == nil {
+			configs[config.Path] = fmt.Sprintf("%x", sha1.Sum(data))
+		}
+	}
+	return configs
+}
+
+// readMrpack turns a modrinth modpack into an instance, mods are identified by the project and version ids in their download urls
+func readMrpack(file string) (i util.Instance, c map[string]string, e error) {
+	reader, err := zip.OpenReader(file)
+	if err != nil {
+		return util.Instance{}, nil, err
+	}
+	defer reader.Close()
+
+	var index mrpackIndex
+	configs := map[string]string{}
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		in, err1 := f.Open()
+		if err1 != nil {
+			return util.Instance{}, nil, err1
+		}
+		data, err2 := ioutil.ReadAll(in)
+		in.Close()
+		if err2 != nil {
+			return util.Instance{}, nil, err2
+		}
+
+		if f.Name == "modrinth.index.json" {
+			if err3 := json.Unmarshal(data, &index); err3 != nil {
+				return util.Instance{}, nil, err3
+			}
+		}
+
+		for _, prefix := range []string{"overrides/config/", "client-overrides/config/", "server-overrides/config/"} {
+			if strings.HasPrefix(f.Name, prefix) {
+				configs[strings.TrimPrefix(f.Name, prefix)] = fmt.Sprintf("%x", sha1.Sum(data))
+			}
+		}
+	}
+
+	instance := util.Instance{Name: index.Name, Version: index.Dependencies["minecraft"]}
+	for _, loader := range []string{"fabric", "quilt"} {
+		if version, ok := index.Dependencies[loader+"-loader"]; ok {
+			instance.Loader = loader
+			instance.LoaderVersion = version
+		}
+	}
+
+	for _, f := range index.Files {
+		if !strings.HasPrefix(f.Path, "mods/") {
+			continue
+		}
+
+		mod := util.ModData{Filename: filepath.Base(f.Path), Name: strings.TrimSuffix(filepath.Base(f.Path), ".jar")}
+		for _, url := range f.Downloads {
+			if match := modrinthCdnRegex.FindStringSubmatch(url); match != nil {
+				mod.Platform = "modrinth"
+				mod.ProjectId = match[1]
+				mod.Id = match[2]
+			}
+		}
+		instance.Mods = append(instance.Mods, mod)
+	}
+	return instance, configs, nil
+}
+
+// LoadDiffSource reads an instance name, an exported instance json, a .lock file or a .mrpack into an instance and the hashes
+// of its config files. The mods of instances and lockfiles carry the sha1 of their installed file as hash.
+// The configs are nil when the source does not know them, so they are left out of the diff
+func LoadDiffSource(source string) (i util.Instance, c map[string]string, e error) {
+	if strings.HasSuffix(source, ".mrpack") {
+		return readMrpack(source)
+	}
+
+	if strings.HasSuffix(source, ".lock") {
+		return readLockfile(source)
+	}
+
+	if strings.HasSuffix(source, ".json") {
+		data, err := ioutil.ReadFile(source)
+		if err != nil {
+			return util.Instance{}, nil, err
+		}
+
+		var instance util.Instance
+		if err1 := json.Unmarshal(data, &instance); err1 != nil {
+			return util.Instance{}, nil, err1
+		}
+
+		//Exports from before configs were tracked do not know them
+		if instance.Configs == nil {
+			return instance, nil, nil
+		}
+
+		//Exports carry the defaults of their tracked configs
+		configs := map[string]string{}
+		for _, config := range instance.Configs {
+			if rendered, err2 := renderConfig(instance, config); err2 == nil {
+				configs[config.Path] = fmt.Sprintf("%x", sha1.Sum([]byte(rendered)))
+			}
+		}
+		return instance, configs, nil
+	}
+
+	instance, err := GetInstance(source)
+	if err != nil {
+		return util.Instance{}, nil, err
+	}
+	configs := hashConfigs(instance)
+	instance.Mods = hashModFiles(instance)
+	return instance, configs, nil
+}
+
+func isSameMod(a util.ModData, b util.ModData) bool {
+	if a.ProjectId != "" && b.ProjectId != "" {
+		return a.ProjectId == b.ProjectId
+	}
+	if a.Slug != "" && b.Slug != "" {
+		return a.Slug == b.Slug
+	}
+	return strings.EqualFold(a.Name, b.Name)
+}
+
+// isOtherFile compares the hashes of two mods, which only works when both know theirs and used the same algorithm
+func isOtherFile(a util.ModData, b util.ModData) bool {
+	return a.Hash != "" && len(a.Hash) == len(b.Hash) && !strings.EqualFold(a.Hash, b.Hash)
+}
+
+func getDisplayVersion(mod util.ModData) string {
+	if mod.Version != "" {
+		return mod.Version
+	}
+	if mod.Filename != "" {
+		return mod.Filename
+	}
+	return mod.Id
+}
+
+// DiffInstances lists what changed going from instance a to instance b, configs are only compared when both sides know them
+func DiffInstances(a util.Instance, aConfigs map[string]string, b util.Instance, bConfigs map[string]string) InstanceDiff {
+	var diff InstanceDiff
+	if a.Version != b.Version {
+		diff.GameVersion = []string{a.Version, b.Version}
+	}
+	if a.Loader != b.Loader {
+		diff.Loader = []string{a.Loader, b.Loader}
+	}
+	if a.LoaderVersion != b.LoaderVersion {
+		diff.LoaderVersion = []string{a.LoaderVersion, b.LoaderVersion}
+	}
+
+	for _, mod := range a.Mods {
+		found := false
+		for _, other := range b.Mods {
+			if !isSameMod(mod, other) {
+				continue
+			}
+			found = true
+
+			changed := mod.Id != other.Id
+			if mod.Id == "" || other.Id == "" {
+				changed = mod.Filename != other.Filename
+			}
+
+			if changed {
+				diff.Changed = append(diff.Changed, ModChange{Name: mod.Name, OldVersion: getDisplayVersion(mod), NewVersion: getDisplayVersion(other)})
+			} else if isOtherFile(mod, other) {
+				diff.Changed = append(diff.Changed, ModChange{Name: mod.Name, OldVersion: getDisplayVersion(mod), NewVersion: getDisplayVersion(other) + " (different file)"})
+			}
+			break
+		}
+
+		if !found {
+			diff.Removed = append(diff.Removed, mod)
+		}
+	}
+
+	for _, mod := range b.Mods {
+		found := false
+		for _, other := range a.Mods {
+			if isSameMod(mod, other) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			diff.Added = append(diff.Added, mod)
+		}
+	}
+
+	if aConfigs == nil || bConfigs == nil {
+		return diff
+	}
+
+	for file, hash := range aConfigs {
+		if otherHash, ok := bConfigs[file]; !ok {
+			diff.Configs = append(diff.Configs, ConfigChange{File: file, Change: "removed"})
+		} else if otherHash != hash {
+			diff.Configs = append(diff.Configs, ConfigChange{File: file, Change: "changed"})
+		}
+	}
+	for file := range bConfigs {
+		if _, ok := aConfigs[file]; !ok {
+			diff.Configs = append(diff.Configs, ConfigChange{File: file, Change: "added"})
+		}
+	}
+	return diff
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+)
+
+type DependencyNode struct {
+	Name         string
+	Dependencies []DependencyNode
+}
+
+// FindMod finds an installed mod by its name, slug or project id
+func FindMod(instance util.Instance, arg string) (m util.ModData, found bool) {
+	for _, mod := range instance.Mods {
+		if strings.EqualFold(mod.Name, arg) || strings.EqualFold(mod.Slug, arg) || mod.ProjectId == arg {
+			return mod, true
+		}
+	}
+	return util.ModData{}, false
+}
+
+// GetInstalledModJson reads the fabric.mod.json of an installed mod's jar
+func GetInstalledModJson(instance util.Instance, mod util.ModData) (m fileutils.ModJson, e error) {
+	file := GetModFile(instance, mod)
+	if _, err := os.Stat(file); err != nil || mod.Filename == "" {
+		return fileutils.ModJson{}, errors.New("mod jar is missing")
+	}
+	return fileutils.GetModJsonFromJar(file)
+}
+
+// GetDependencyTree resolves the required dependencies of a mod for a loader and game version, depth levels deep
+func GetDependencyTree(mod util.ModData, loader string, version string, depth int) DependencyNode {
+	return getDependencyTree(mod, loader, version, depth, []string{mod.ProjectId})
+}
+
+func getDependencyTree(mod util.ModData, loader string, version string, depth int, seen []string) DependencyNode {
+	node := DependencyNode{Name: mod.Name}
+	if depth == 0 {
+		return node
+	}
+
+	modData, err := fetchModData(mod, loader, version)
+	if err != nil {
+		return node
+	}
+	node.Name = modData.Name
+
+	for _, dep := range modData.Dependencies {
+		if !dep.Required || dep.ProjectId == "" || util.Contains(seen, dep.ProjectId) {
+			continue
+		}
+
+		depMod := util.ModData{Platform: mod.Platform, ProjectId: dep.ProjectId, Name: dep.Name}
+		node.Dependencies = append(node.Dependencies, getDependencyTree(depMod, loader, version, depth-1, append(seen, dep.ProjectId)))
+	}
+	return node
+}
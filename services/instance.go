@@ -14,7 +14,6 @@ import (
 	"github.com/mrnavastar/modman/util"
 	"github.com/mrnavastar/modman/util/fileutils"
 	"github.com/pterm/pterm"
-	"golang.org/x/mod/semver"
 )
 
 func CreateInstance(name string, loader string, version string) error {
@@ -44,7 +43,7 @@ func CreateInstance(name string, loader string, version string) error {
 	profile.Icon = "Crafting_Table"
 	profile.Created = time
 	profile.LastUsed = time
-	profile.JavaArgs = "-Xmx2G -XX:+UnlockExperimentalVMOptions -XX:+UseG1GC -XX:G1NewSizePercent=20 -XX:G1ReservePercent=20 -XX:MaxGCPauseMillis=50 -XX:G1HeapRegionSize=32M"
+	profile.JavaArgs = getProfileJavaArgs(instance)
 
 	if loader == "fabric" {
 		lversion, err1 := api.GetLatestFabricLoaderVersion()
@@ -52,23 +51,281 @@ func CreateInstance(name string, loader string, version string) error {
 		instance.LoaderVersion = lversion
 
 		api.DownloadFabricJson(&state, version, lversion)
-		profile.JavaArgs += " -Dfabric.addMods=" + instance.Path
 	} else if loader == "quilt" {
 		lversion := api.GetLatestQuiltLoaderVersion()
 		instance.LoaderVersion = lversion
 
 		api.DownloadQuiltJson(&state, version, lversion)
-		profile.JavaArgs += " -Dloader.modsDir=" + instance.Path
 	}
 
 	state.Instances = append(state.Instances, instance)
-	profile.LastVersionId = loader + "-loader-" + instance.LoaderVersion + "-" + version
+	profile.LastVersionId = getVersionId(instance)
 
 	fileutils.AddProfile(profile)
 	fileutils.SaveAppState(state)
 	return nil
 }
 
+// isFolderName checks that a name can be used as a single folder inside the instances folder
+func isFolderName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\")
+}
+
+// CloneInstance copies an instance with its mods, settings and launcher profile under a new name
+func CloneInstance(src string, dst string) error {
+	if !isFolderName(dst) {
+		return errors.New("invalid instance name")
+	}
+
+	state := fileutils.LoadAppState()
+
+	for _, instance := range state.Instances {
+		if strings.EqualFold(instance.Name, dst) {
+			return errors.New("already instance with that name")
+		}
+	}
+
+	oldInstance, err := GetInstance(src)
+	if err != nil {
+		return err
+	}
+
+	instance := oldInstance
+	instance.Name = dst
+	instance.Path = state.WorkDir + "/instances/" + dst
+	instance.Mods = append([]util.ModData(nil), oldInstance.Mods...)
+
+	//A folder modman does not track may hold someone's files, it is never copied over or removed
+	if _, err1 := os.Stat(instance.Path); !os.IsNotExist(err1) {
+		return errors.New("instance folder already exists")
+	}
+
+	if err1 := fileutils.CopyDir(oldInstance.Path, instance.Path); err1 != nil {
+		os.RemoveAll(instance.Path)
+		return err1
+	}
+
+	if !instance.Server {
+		time := time.Now().Format(time.RFC3339)
+		profile, err2 := fileutils.GetProfile(oldInstance.Name)
+		if err2 != nil {
+			profile = util.Profile{Type: "custom", Icon: "Crafting_Table", LastVersionId: getVersionId(instance)}
+		}
+
+		profile.Name = dst
+		profile.Created = time
+		profile.LastUsed = time
+		profile.JavaArgs = getProfileJavaArgs(instance)
+		if profile.GameDir != "" {
+			profile.GameDir = strings.Replace(profile.GameDir, oldInstance.Path, instance.Path, 1)
+		}
+		fileutils.AddProfile(profile)
+	}
+
+	state.Instances = append(state.Instances, instance)
+	fileutils.SaveAppState(state)
+	return nil
+}
+
+// RenameInstance moves the folder and launcher profile of an instance to a new name, undoing every step if one fails
+func RenameInstance(oldName string, newName string) error {
+	state := fileutils.LoadAppState()
+
+	for _, instance := range state.Instances {
+		if strings.EqualFold(instance.Name, newName) && !strings.EqualFold(instance.Name, oldName) {
+			return errors.New("already instance with that name")
+		}
+	}
+
+	oldInstance, err := GetInstance(oldName)
+	if err != nil {
+		return err
+	}
+
+	instance := oldInstance
+	instance.Name = newName
+	instance.Path = state.WorkDir + "/instances/" + newName
+
+	if err1 := os.Rename(oldInstance.Path, instance.Path); err1 != nil {
+		return err1
+	}
+
+	oldProfile, err2 := fileutils.GetProfile(oldInstance.Name)
+	hasProfile := err2 == nil && !instance.Server
+	if hasProfile {
+		profile := oldProfile
+		profile.Name = newName
+		profile.JavaArgs = getProfileJavaArgs(instance)
+		if profile.GameDir != "" {
+			profile.GameDir = strings.Replace(profile.GameDir, oldInstance.Path, instance.Path, 1)
+		}
+
+		if err3 := fileutils.RenameProfile(oldInstance.Name, profile); err3 != nil {
+			os.Rename(instance.Path, oldInstance.Path)
+			return err3
+		}
+	}
+
+	for i, in := range state.Instances {
+		if in.Name == oldInstance.Name {
+			state.Instances[i] = instance
+			break
+		}
+	}
+	if strings.EqualFold(state.ActiveInstance, oldInstance.Name) {
+		state.ActiveInstance = newName
+	}
+
+	if err4 := fileutils.WriteAppState(state); err4 != nil {
+		if hasProfile {
+			fileutils.RenameProfile(newName, oldProfile)
+		}
+		os.Rename(instance.Path, oldInstance.Path)
+		return err4
+	}
+	return nil
+}
+
+// isolatedFolders are created in the game folder of an isolated instance
+var isolatedFolders = []string{"mods", "config", "saves", "resourcepacks", "shaderpacks"}
+
+// IsolateInstance gives a client instance a game folder of its own instead of the shared .minecraft. Its mods move into
+// the mods folder and the packs it has are copied over, datapacks together with their world. With copyShared the configs,
+// saves and options of .minecraft are copied as well
+func IsolateInstance(name string, copyShared bool) error {
+	instance, err := GetInstance(name)
+	if err != nil {
+		return err
+	}
+
+	if instance.Server {
+		return errors.New("server instances are always isolated")
+	}
+	if instance.Isolated {
+		return errors.New("instance already isolated")
+	}
+
+	shared := instance
+	instance.Isolated = true
+	for _, folder := range isolatedFolders {
+		if err1 := os.MkdirAll(instance.Path+"/"+folder, 0700); err1 != nil {
+			return err1
+		}
+	}
+
+	if copyShared {
+		dotMinecraft := fileutils.LoadAppState().DotMinecraft
+		for _, file := range []string{"config", "saves", "options.txt", "servers.dat"} {
+			if _, err1 := os.Stat(dotMinecraft + "/" + file); err1 != nil {
+				continue
+			}
+			if err2 := fileutils.CopyDir(dotMinecraft+"/"+file, instance.Path+"/"+file); err2 != nil {
+				return err2
+			}
+		}
+	}
+
+	if err1 := copyConfigs(shared, instance); err1 != nil {
+		return err1
+	}
+	writeConfigs(instance)
+
+	for _, mod := range instance.Mods {
+		file := GetModFile(instance, mod)
+		if _, err1 := os.Stat(file); mod.Type == "" || err1 == nil {
+			continue
+		}
+
+		if mod.Type == "datapack" {
+			if _, err2 := os.Stat(getWorldFolder(instance, mod.World)); err2 != nil {
+				if err3 := fileutils.CopyDir(getWorldFolder(shared, mod.World), getWorldFolder(instance, mod.World)); err3 != nil {
+					return err3
+				}
+				continue
+			}
+		}
+
+		if err2 := os.MkdirAll(filepath.Dir(file), 0700); err2 != nil {
+			return err2
+		}
+		if err3 := fileutils.CopyFile(GetModFile(shared, mod), file); err3 != nil {
+			return err3
+		}
+	}
+
+	profile, err1 := fileutils.GetProfile(instance.Name)
+	if err1 != nil {
+		return err1
+	}
+
+	//Moving the jars is the only step that changes the shared instance, so it is undone when saving fails
+	var moved []string
+	restore := func() {
+		for _, jar := range moved {
+			os.Rename(GetModFolder(instance)+"/"+filepath.Base(jar), jar)
+		}
+	}
+
+	//Every jar in the instance folder is a mod, including ones modman does not track
+	jars, _ := filepath.Glob(shared.Path + "/*.jar")
+	for _, jar := range jars {
+		if err2 := os.Rename(jar, GetModFolder(instance)+"/"+filepath.Base(jar)); err2 != nil {
+			restore()
+			return err2
+		}
+		moved = append(moved, jar)
+	}
+
+	if err2 := SaveInstance(instance); err2 != nil {
+		restore()
+		return err2
+	}
+
+	profile.GameDir = instance.Path
+	profile.JavaArgs = getProfileJavaArgs(instance)
+	fileutils.AddProfile(profile)
+	return nil
+}
+
+// installLoader downloads the loader profile json of a client instance and points its launcher profile at it,
+// server instances get a new server jar and loader launcher instead
+func installLoader(instance util.Instance) error {
+	state := fileutils.LoadAppState()
+
+	if instance.Server {
+		if err := api.DownloadServerJar(instance.Version, instance.Path+"/server.jar"); err != nil {
+			return err
+		}
+
+		if instance.Loader == "fabric" {
+			if err := api.InstallFabricServer(instance.Version, instance.LoaderVersion, instance.Path); err != nil {
+				return err
+			}
+		} else if instance.Loader == "quilt" {
+			if err := api.InstallQuiltServer(instance.Version, instance.LoaderVersion, instance.Path); err != nil {
+				return err
+			}
+		}
+		writeStartScripts(instance)
+		return nil
+	}
+
+	if instance.Loader == "fabric" {
+		api.DownloadFabricJson(&state, instance.Version, instance.LoaderVersion)
+	} else if instance.Loader == "quilt" {
+		api.DownloadQuiltJson(&state, instance.Version, instance.LoaderVersion)
+	}
+
+	profile, err := fileutils.GetProfile(instance.Name)
+	if err != nil {
+		return err
+	}
+	profile.LastVersionId = getVersionId(instance)
+	profile.JavaArgs = getProfileJavaArgs(instance)
+	fileutils.AddProfile(profile)
+	return nil
+}
+
 func DeleteInstance(name string) {
 	state := fileutils.LoadAppState()
 
@@ -120,15 +377,25 @@ func SaveInstance(instance util.Instance) error {
 	return errors.New("failed to find instance")
 }
 
+// GetModFolder returns the folder the mod jars of an instance live in
+func GetModFolder(instance util.Instance) string {
+	if instance.Server || instance.Isolated {
+		return instance.Path + "/mods"
+	}
+	return instance.Path
+}
+
 func isModDownloaded(instance *util.Instance, modData util.ModData) bool {
 	for _, mod := range instance.Mods {
-		if mod.ProjectId == modData.ProjectId {
+		if mod.ProjectId == modData.ProjectId && mod.Type == modData.Type && mod.World == modData.World {
 			return true
 		}
 	}
 	return false
 }
 
+// GetModsRelyOn returns the names of the installed mods that need a mod, either through their platform dependencies
+// or through the depends block of their fabric.mod.json
 func GetModsRelyOn(instance *util.Instance, slug string) []string {
 	var mod util.ModData
 	for _, m := range instance.Mods {
@@ -136,49 +403,150 @@ func GetModsRelyOn(instance *util.Instance, slug string) []string {
 			mod = m
 		}
 	}
+	modJson, _ := GetInstalledModJson(*instance, mod)
 
 	var mods []string
 	for _, m := range instance.Mods {
+		if m.Id == mod.Id {
+			continue
+		}
+
+		relies := false
 		for _, dep := range m.Dependencies {
 			if mod.ProjectId == dep.ProjectId {
-				mods = append(mods, m.Name)
+				relies = true
+			}
+		}
+
+		if !relies && modJson.Id != "" {
+			if otherJson, err := GetInstalledModJson(*instance, m); err == nil {
+				_, relies = otherJson.Depends[modJson.Id]
 			}
 		}
+
+		if relies {
+			mods = append(mods, m.Name)
+		}
 	}
 	return mods
 }
 
+// getEnvironmentSides converts the environment field of a fabric.mod.json into client and server side support
+func getEnvironmentSides(environment string) (client string, server string) {
+	switch environment {
+	case "client":
+		return "required", "unsupported"
+	case "server":
+		return "unsupported", "required"
+	case "*":
+		return "required", "required"
+	}
+	return "", ""
+}
+
+// IsServerMod returns false only for mods known to be client side only
+func IsServerMod(mod util.ModData) bool {
+	return mod.ServerSide != "unsupported"
+}
+
 // AddMod Must call SaveInstance after using! - this allows for batching mod installations into one file write call
 func AddMod(instance *util.Instance, arg string, modData util.ModData, isUpdate bool) error {
 	slug := strings.Replace(arg, "c:", "", -1)
 
 	if modData.Id == "" {
+		packType, world := modData.Type, modData.World
+
 		//Check if slug is int
-		if _, err := strconv.Atoi(slug); err == nil || strings.Contains(arg, "c:") {
-			m, err1 := api.GetCurseModData(slug, instance.Version)
+		if strings.HasPrefix(arg, "url:") {
+			m, _, err1 := getUrlModData(strings.TrimPrefix(arg, "url:"))
+			if err1 != nil {
+				return err1
+			}
+			m.CheckUrl = modData.CheckUrl
+			modData = m
+		} else if strings.HasPrefix(arg, "file:") {
+			m, _, err1 := getFileModData(strings.TrimPrefix(arg, "file:"))
 			if err1 != nil {
 				return err1
 			}
 			modData = m
+		} else if strings.HasPrefix(arg, "maven:") {
+			m, err1 := api.GetMavenModData(strings.TrimPrefix(arg, "maven:"), instance.Loader, instance.Version)
+			if err1 != nil {
+				return err1
+			}
+			modData = m
+		} else if strings.HasPrefix(arg, "gh:") {
+			m, err1 := api.GetGithubModData(strings.TrimPrefix(arg, "gh:"), instance.Version, modData.AssetPattern)
+			if err1 != nil {
+				return err1
+			}
+			modData = m
+		} else if _, err := strconv.Atoi(slug); err == nil || strings.Contains(arg, "c:") {
+			m, err1 := api.GetCurseModData(slug, instance.Loader, instance.Version, packType)
+			if err1 != nil && err1.Error() != "distribution disabled" {
+				return err1
+			}
+			modData = m
 		} else {
-			m, err1 := api.GetModrinthModData(slug, instance.Loader, instance.Version)
+			m, err1 := api.GetModrinthModData(slug, instance.Loader, instance.Version, packType)
 			if err1 != nil {
 				return err1
 			}
 			modData = m
 		}
+
+		if modData.Type != "" {
+			modData.World = world
+			w, err1 := resolvePackWorld(*instance, modData)
+			if err1 != nil {
+				return err1
+			}
+			modData.World = w
+			modData.ClientSide, modData.ServerSide = getPackSides(modData.Type)
+		}
 	}
 
 	if isModDownloaded(instance, modData) {
 		return errors.New("mod already added")
 	}
 
-	file := instance.Path + "/" + modData.Filename
-	fileutils.DownloadFile(modData.Url, file)
-	modJson, err := fileutils.GetModJsonFromJar(file)
-	util.Fatal(err)
+	if instance.Server && !IsServerMod(modData) {
+		return errors.New("client only mod")
+	}
+
+	//Curseforge mods whose authors disabled third party downloads have no url and must be provided by hand
+	if modData.Url == "" {
+		addManualMod(instance, modData)
+		return errors.New("distribution disabled")
+	}
+
+	file := GetModFile(*instance, modData)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	if err := downloadModFile(modData, file); err != nil {
+		return err
+	}
+	installModFile(instance, modData, file, isUpdate)
+	return nil
+}
 
-	modData.Version = modJson.Version
+// installModFile adds a mod whose jar is already in the mod folder to an instance, installing its dependencies unless it is an update.
+// Packs have no fabric.mod.json and keep the version of their platform
+func installModFile(instance *util.Instance, modData util.ModData, file string, isUpdate bool) {
+	if modData.Type == "" {
+		//Mods without a fabric.mod.json, like quilt only ones, keep what their platform knows about them
+		if modJson, err := fileutils.GetModJsonFromJar(file); err == nil {
+			//Github and maven mods keep the version they were published under so updates can compare it
+			if modData.Platform != "github" && modData.Platform != "maven" {
+				modData.Version = modJson.Version
+			}
+			if modData.ClientSide == "" && modData.ServerSide == "" {
+				modData.ClientSide, modData.ServerSide = getEnvironmentSides(modJson.Environment)
+			}
+		}
+	}
 	instance.Mods = append(instance.Mods, modData)
 
 	if !isUpdate {
@@ -193,7 +561,7 @@ func AddMod(instance *util.Instance, arg string, modData util.ModData, isUpdate
 		//}
 
 		for _, dep := range modData.Dependencies {
-			if dep.Required {
+			if dep.Required && dep.ProjectId != "" {
 				err := AddMod(instance, dep.ProjectId, util.ModData{}, false)
 				if err != nil && err.Error() == "failed to get mod data" {
 					pterm.Error.Println("Failed to download dependency for " + modData.Name + ": " + dep.Name)
@@ -203,7 +571,6 @@ func AddMod(instance *util.Instance, arg string, modData util.ModData, isUpdate
 	} else {
 		pterm.Success.Println("Updated " + modData.Name)
 	}
-	return nil
 }
 
 // RemoveMod Must call SaveInstanceData after using! - this allows for batching mod removals into one file write call
@@ -211,7 +578,12 @@ func RemoveMod(instance *util.Instance, id string) {
 	mods := instance.Mods
 	for i, mod := range mods {
 		if mod.Id == id {
-			util.Fatal(os.Remove(instance.Path + "/" + mod.Filename))
+			//A file that is already gone is as good as removed
+			if file := GetModFile(*instance, mod); !isPackShared(*instance, mod, file) {
+				if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+					util.Fatal(err)
+				}
+			}
 
 			//Remove item
 			mods[i] = mods[len(mods)-1]
@@ -221,54 +593,73 @@ func RemoveMod(instance *util.Instance, id string) {
 	}
 }
 
-func UpdateInstance(state *fileutils.State, name string) {
+func UpdateInstance(name string) {
 	instance, err := GetInstance(name)
 	util.Fatal(err)
 
-	if instance.Loader == "fabric" {
-		lversion, err1 := api.GetLatestFabricLoaderVersion()
-		util.Fatal(err1)
-
-		if semver.Compare(instance.LoaderVersion, lversion) == -1 {
-			api.DownloadFabricJson(state, instance.Version, lversion)
-			instance.Loader = lversion
-		}
-	} else if instance.Loader == "quilt" {
-		lversion := api.GetLatestQuiltLoaderVersion()
-
-		if semver.Compare(instance.LoaderVersion, lversion) == -1 {
-			api.DownloadQuiltJson(state, instance.Version, lversion)
-			instance.Loader = lversion
+	if !instance.LoaderPinned {
+		lversion, err1 := GetLatestLoaderVersion(instance.Loader)
+		if err1 == nil && IsNewerVersion(instance.LoaderVersion, lversion) {
+			util.Fatal(SetLoaderVersion(&instance, lversion))
+			pterm.Success.Println("Updated " + instance.Loader + " loader to " + lversion)
 		}
 	}
 
 	//Update mods
-	for _, mod := range instance.Mods {
-		var modData util.ModData
-		if mod.Platform == "modrinth" {
-			m, err := api.GetModrinthModData(modData.Slug, instance.Loader, instance.Version)
-			if err != nil {
-				continue
-			}
-			modData = m
+	for _, mod := range append([]util.ModData(nil), instance.Mods...) {
+		if mod.Pinned {
+			continue
 		}
 
-		if mod.Platform == "curse" {
-			m, err := api.GetCurseModData(modData.Slug, instance.Version)
-			if err != nil {
-				continue
+		modData, err1 := fetchModData(mod, instance.Loader, instance.Version)
+		if err1 != nil {
+			if err1.Error() == "distribution disabled" && mod.Id != modData.Id {
+				addManualMod(&instance, modData)
+			} else if err1.Error() != "distribution disabled" && err1.Error() != "failed to find matching version" {
+				pterm.Error.Println("Failed to check " + mod.Name + " for updates: " + err1.Error())
 			}
-			modData = m
+			continue
 		}
 
 		if mod.Id != modData.Id {
-			RemoveMod(&instance, mod.Id)
-			util.Fatal(AddMod(&instance, "", modData, true))
+			if err2 := updateMod(&instance, mod, modData); err2 != nil {
+				pterm.Error.Println("Failed to update " + mod.Name + ": " + err2.Error())
+			}
 		}
 	}
 	util.Fatal(SaveInstance(instance))
 }
 
+// updateMod swaps a mod for another release. The new file is downloaded and checked next to the old one first, so a failed
+// download leaves the mod as it was
+func updateMod(instance *util.Instance, mod util.ModData, modData util.ModData) error {
+	if instance.Server && !IsServerMod(modData) {
+		return errors.New("client only mod")
+	}
+
+	//Curseforge mods whose authors disabled third party downloads keep the old file until the new one is provided by hand
+	if modData.Url == "" {
+		addManualMod(instance, modData)
+		return nil
+	}
+
+	file := GetModFile(*instance, modData)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	if err := downloadModFile(modData, file+".part"); err != nil {
+		os.Remove(file + ".part")
+		return err
+	}
+
+	RemoveMod(instance, mod.Id)
+	if err := os.Rename(file+".part", file); err != nil {
+		return err
+	}
+	installModFile(instance, modData, file, true)
+	return nil
+}
+
 func ExportInstance(instance util.Instance) {
 	state := fileutils.LoadAppState()
 	instance.Path = ""
@@ -284,38 +675,119 @@ func ExportInstance(instance util.Instance) {
 	util.Fatal(err2)
 }
 
-func ImportInstance(file string) string {
+// ExportServerMods copies every server compatible mod jar of an instance into dir and its tracked configs into dir/config.
+// The client only mods and the configs whose template could not be rendered are returned
+func ExportServerMods(instance util.Instance, dir string) (m []util.ModData, c []string) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		util.Fatal(os.MkdirAll(dir, 0700))
+	}
+
+	var skipped []util.ModData
+	for _, mod := range instance.Mods {
+		if mod.Type != "" {
+			continue
+		}
+
+		if !IsServerMod(mod) {
+			skipped = append(skipped, mod)
+			continue
+		}
+		util.Fatal(fileutils.CopyFile(GetModFile(instance, mod), dir+"/"+mod.Filename))
+	}
+	return skipped, exportConfigs(instance, dir+"/config")
+}
+
+// ImportInstance creates an instance from an exported json with the same loader version, pin, mods and configs
+func ImportInstance(file string) (n string, e error) {
 	data, err := ioutil.ReadFile(file)
-	util.Fatal(err)
+	if err != nil {
+		return "", err
+	}
 
 	var instanceData util.Instance
-	err2 := json.Unmarshal(data, &instanceData)
-	util.Fatal(err2)
+	if err1 := json.Unmarshal(data, &instanceData); err1 != nil {
+		return "", err1
+	}
+
+	//Configs are only tracked in a config folder of the instance's own, and never outside of it
+	if len(instanceData.Configs) > 0 && !instanceData.Isolated && !instanceData.Server {
+		return "", errors.New("shared config folder")
+	}
+	configs, err2 := normalizeConfigs(instanceData, instanceData.Configs)
+	if err2 != nil {
+		return "", err2
+	}
 
-	CreateInstance(instanceData.Name, instanceData.Loader, instanceData.Version)
+	if instanceData.Server {
+		if err2 := CreateServerInstance(instanceData.Name, instanceData.Loader, instanceData.Version); err2 != nil {
+			return "", err2
+		}
+	} else {
+		if err2 := CreateInstance(instanceData.Name, instanceData.Loader, instanceData.Version); err2 != nil {
+			return "", err2
+		}
+		if instanceData.Isolated {
+			if err3 := IsolateInstance(instanceData.Name, false); err3 != nil {
+				return "", err3
+			}
+		}
+	}
 	instance, err2 := GetInstance(instanceData.Name)
-	util.Fatal(err2)
+	if err2 != nil {
+		return "", err2
+	}
+
+	//New instances get the latest loader, the exported one is put back so a pinned version stays pinned
+	if instanceData.LoaderVersion != "" && instanceData.LoaderVersion != instance.LoaderVersion {
+		if err3 := SetLoaderVersion(&instance, instanceData.LoaderVersion); err3 != nil {
+			return "", err3
+		}
+	}
+	instance.LoaderPinned = instanceData.LoaderPinned
 
 	for _, mod := range instanceData.Mods {
 		mod.Dependencies = nil
 		AddMod(&instance, "", mod, false)
 	}
 
-	util.Fatal(SaveInstance(instance))
-	return instance.Name
+	instance.Configs = configs
+	instance.ConfigVars = instanceData.ConfigVars
+	for _, config := range writeConfigs(instance) {
+		pterm.Warning.Println("Kept your config/" + config + " ~ modman config diff " + config)
+	}
+	return instance.Name, SaveInstance(instance)
 }
 
-func ImportMods(instance *util.Instance, folder string) {
-	var mods []string
+// ImportMods Must call SaveInstance after using! - adds the jars in a folder that curseforge recognizes by fingerprint
+func ImportMods(instance *util.Instance, folder string) []string {
+	var unknown []string
 	filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
 		util.Fatal(err)
 
-		if _, err = fileutils.GetModJsonFromJar(path); err == nil {
-			mods = append(mods, path)
+		if info.IsDir() || !strings.HasSuffix(path, ".jar") {
+			return nil
 		}
 
+		fingerprint, err1 := fileutils.GetCurseFingerprint(path)
+		util.Fatal(err1)
+
+		modData, err2 := api.GetCurseModDataByFingerprint(fingerprint)
+		if err2 != nil && err2.Error() != "distribution disabled" {
+			unknown = append(unknown, path)
+			return nil
+		}
+
+		if isModDownloaded(instance, modData) {
+			return nil
+		}
+
+		util.Fatal(fileutils.CopyFile(path, GetModFolder(*instance)+"/"+modData.Filename))
+		if modJson, err3 := fileutils.GetModJsonFromJar(path); err3 == nil {
+			modData.Version = modJson.Version
+		}
+		instance.Mods = append(instance.Mods, modData)
+		pterm.Success.Println("Imported " + modData.Name)
 		return nil
 	})
-
-	util.Fatal(SaveInstance(*instance))
+	return unknown
 }
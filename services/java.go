@@ -0,0 +1,213 @@
+package services
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+)
+
+const defaultMemory = "2G"
+const defaultJvmArgs = "-XX:+UnlockExperimentalVMOptions -XX:+UseG1GC -XX:G1NewSizePercent=20 -XX:G1ReservePercent=20 -XX:MaxGCPauseMillis=50 -XX:G1HeapRegionSize=32M"
+
+type JavaInstall struct {
+	Path    string
+	Version int
+}
+
+var javaVersionRegex = regexp.MustCompile(`version "([^"]+)"`)
+
+// parseJavaVersion turns both old (1.8.0_292) and new (17.0.2) version strings into a major version
+func parseJavaVersion(version string) int {
+	parts := strings.Split(version, ".")
+	if parts[0] == "1" && len(parts) > 1 {
+		parts = parts[1:]
+	}
+
+	major, err := strconv.Atoi(strings.Split(strings.Split(parts[0], "-")[0], "+")[0])
+	if err != nil {
+		return 0
+	}
+	return major
+}
+
+// getJavaVersion reads the major version of a java executable from the release file next to it, or from java -version
+func getJavaVersion(java string) int {
+	if resolved, err := filepath.EvalSymlinks(java); err == nil {
+		java = resolved
+	}
+
+	release, err := ioutil.ReadFile(filepath.Join(filepath.Dir(filepath.Dir(java)), "release"))
+	if err == nil {
+		for _, line := range strings.Split(string(release), "\n") {
+			if strings.HasPrefix(line, "JAVA_VERSION=") {
+				return parseJavaVersion(strings.Trim(strings.TrimPrefix(line, "JAVA_VERSION="), "\"\r"))
+			}
+		}
+	}
+
+	output, err1 := exec.Command(java, "-version").CombinedOutput()
+	if err1 != nil {
+		return 0
+	}
+
+	match := javaVersionRegex.FindStringSubmatch(string(output))
+	if match == nil {
+		return 0
+	}
+	return parseJavaVersion(match[1])
+}
+
+func getJavaExecutable(home string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "bin", "java.exe")
+	}
+	return filepath.Join(home, "bin", "java")
+}
+
+// getJavaSearchDirs returns folders that usually hold one jdk per sub folder
+func getJavaSearchDirs() []string {
+	home, _ := os.UserHomeDir()
+	dirs := []string{filepath.Join(home, ".sdkman", "candidates", "java"), filepath.Join(home, ".jdks")}
+
+	switch runtime.GOOS {
+	case "windows":
+		for _, programFiles := range []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)")} {
+			if programFiles != "" {
+				dirs = append(dirs, filepath.Join(programFiles, "Java"), filepath.Join(programFiles, "Eclipse Adoptium"), filepath.Join(programFiles, "Zulu"), filepath.Join(programFiles, "Microsoft"))
+			}
+		}
+	case "darwin":
+		dirs = append(dirs, "/Library/Java/JavaVirtualMachines", filepath.Join(home, "Library", "Java", "JavaVirtualMachines"))
+	default:
+		dirs = append(dirs, "/usr/lib/jvm", "/usr/java", "/opt/java")
+	}
+	return dirs
+}
+
+// FindJavaInstalls looks for java runtimes in JAVA_HOME, the PATH, the common install folders and the runtimes of the minecraft launcher
+func FindJavaInstalls() []JavaInstall {
+	var candidates []string
+	if javaHome := os.Getenv("JAVA_HOME"); javaHome != "" {
+		candidates = append(candidates, getJavaExecutable(javaHome))
+	}
+
+	if java, err := exec.LookPath("java"); err == nil {
+		candidates = append(candidates, java)
+	}
+
+	for _, dir := range getJavaSearchDirs() {
+		homes, _ := ioutil.ReadDir(dir)
+		for _, home := range homes {
+			candidates = append(candidates, getJavaExecutable(filepath.Join(dir, home.Name())), getJavaExecutable(filepath.Join(dir, home.Name(), "Contents", "Home")))
+		}
+	}
+
+	state := fileutils.LoadAppState()
+	filepath.Walk(state.DotMinecraft+"/runtime", func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && (info.Name() == "java" || info.Name() == "java.exe") && filepath.Base(filepath.Dir(path)) == "bin" {
+			candidates = append(candidates, path)
+		}
+		return nil
+	})
+
+	var installs []JavaInstall
+	var seen []string
+	for _, java := range candidates {
+		if resolved, err := filepath.EvalSymlinks(java); err == nil {
+			java = resolved
+		} else {
+			continue
+		}
+
+		if util.Contains(seen, java) {
+			continue
+		}
+		seen = append(seen, java)
+
+		if version := getJavaVersion(java); version != 0 {
+			installs = append(installs, JavaInstall{Path: java, Version: version})
+		}
+	}
+	return installs
+}
+
+// GetMatchingJava returns the java an instance is set to use, or the first install with the right major version
+func GetMatchingJava(instance util.Instance, required int) (j JavaInstall, found bool) {
+	if instance.JavaPath != "" {
+		java := JavaInstall{Path: instance.JavaPath, Version: getJavaVersion(instance.JavaPath)}
+		return java, java.Version == required
+	}
+
+	for _, java := range FindJavaInstalls() {
+		if java.Version == required {
+			return java, true
+		}
+	}
+	return JavaInstall{}, false
+}
+
+// getJvmArgs returns the memory and jvm arguments an instance should be started with
+func getJvmArgs(instance util.Instance) string {
+	memory := instance.Memory
+	if memory == "" {
+		memory = defaultMemory
+	}
+
+	args := instance.JvmArgs
+	if args == "" {
+		args = defaultJvmArgs
+	}
+	return "-Xmx" + memory + " " + args
+}
+
+// getModsFlag returns the system property pointing the loader of an instance at its mods
+func getModsFlag(instance util.Instance) string {
+	//Isolated instances keep their mods in the mods folder of their game folder, which the loaders read on their own
+	if instance.Isolated {
+		return ""
+	}
+
+	if instance.Loader == "fabric" {
+		return "-Dfabric.addMods=" + GetModFolder(instance)
+	} else if instance.Loader == "quilt" {
+		return "-Dloader.modsDir=" + GetModFolder(instance)
+	}
+	return ""
+}
+
+// getProfileJavaArgs returns the javaArgs of the launcher profile of an instance
+func getProfileJavaArgs(instance util.Instance) string {
+	return strings.TrimSpace(getJvmArgs(instance) + " " + getModsFlag(instance))
+}
+
+// SetJavaSettings Must call SaveInstance after using! - writes the java settings into the launcher profile or the server start scripts
+func SetJavaSettings(instance *util.Instance, memory string, jvmArgs string, javaPath string) {
+	if memory != "" {
+		instance.Memory = memory
+	}
+	if jvmArgs != "" {
+		instance.JvmArgs = jvmArgs
+	}
+	if javaPath != "" {
+		instance.JavaPath = javaPath
+	}
+
+	if instance.Server {
+		writeStartScripts(*instance)
+		return
+	}
+
+	profile, err := fileutils.GetProfile(instance.Name)
+	util.Fatal(err)
+	profile.JavaArgs = getProfileJavaArgs(*instance)
+	profile.JavaDir = instance.JavaPath
+	fileutils.AddProfile(profile)
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"archive/zip"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mrnavastar/modman/api"
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+)
+
+type launchRule struct {
+	Action   string
+	Features map[string]bool
+	Os       struct {
+		Name string
+		Arch string
+	}
+}
+
+type launchArgument struct {
+	Rules []launchRule
+	Value []string
+}
+
+// UnmarshalJSON accepts both plain string arguments and rule guarded objects with one or many values
+func (a *launchArgument) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		a.Value = []string{value}
+		return nil
+	}
+
+	var argument struct {
+		Rules []launchRule
+		Value json.RawMessage
+	}
+	if err := json.Unmarshal(data, &argument); err != nil {
+		return err
+	}
+	a.Rules = argument.Rules
+
+	if err := json.Unmarshal(argument.Value, &value); err == nil {
+		a.Value = []string{value}
+		return nil
+	}
+	return json.Unmarshal(argument.Value, &a.Value)
+}
+
+type launchDownload struct {
+	Path string
+	Url  string
+}
+
+type launchLibrary struct {
+	Name      string
+	Url       string
+	Rules     []launchRule
+	Natives   map[string]string
+	Downloads struct {
+		Artifact    launchDownload
+		Classifiers map[string]launchDownload
+	}
+}
+
+type launchJson struct {
+	Id                 string
+	InheritsFrom       string
+	MainClass          string
+	Type               string
+	MinecraftArguments string
+	Arguments          struct {
+		Game []launchArgument
+		Jvm  []launchArgument
+	}
+	Libraries  []launchLibrary
+	AssetIndex struct {
+		Id  string
+		Url string
+	}
+	Downloads struct {
+		Client launchDownload
+	}
+}
+
+type assetIndex struct {
+	Objects map[string]struct {
+		Hash string
+	}
+}
+
+// getVersionId returns the id of the loader profile in the versions folder of .minecraft
+func getVersionId(instance util.Instance) string {
+	return instance.Loader + "-loader-" + instance.LoaderVersion + "-" + instance.Version
+}
+
+func getOsName() string {
+	if runtime.GOOS == "darwin" {
+		return "osx"
+	}
+	return runtime.GOOS
+}
+
+// isAllowed checks launcher rules against this system, features like demo mode are never enabled
+func isAllowed(rules []launchRule) bool {
+	if len(rules) == 0 {
+		return true
+	}
+
+	allowed := false
+	for _, rule := range rules {
+		if len(rule.Features) > 0 {
+			continue
+		}
+
+		if rule.Os.Name != "" && rule.Os.Name != getOsName() {
+			continue
+		}
+
+		if rule.Os.Arch != "" && (rule.Os.Arch == "x86") != (runtime.GOARCH == "386") {
+			continue
+		}
+		allowed = rule.Action == "allow"
+	}
+	return allowed
+}
+
+func readLaunchJson(dotMinecraft string, id string) (l launchJson, e error) {
+	data, err := ioutil.ReadFile(dotMinecraft + "/versions/" + id + "/" + id + ".json")
+	if err != nil {
+		return launchJson{}, err
+	}
+
+	var launch launchJson
+	err1 := json.Unmarshal(data, &launch)
+	return launch, err1
+}
+
+// getLibraryKey identifies a library without its version, so loader libraries can replace vanilla ones
+func getLibraryKey(name string) string {
+	parts := strings.Split(name, ":")
+	if len(parts) < 3 {
+		return name
+	}
+
+	parts = append(parts[:2], parts[3:]...)
+	return strings.Join(parts, ":")
+}
+
+// downloadMissing downloads a file unless it already exists
+func downloadMissing(url string, file string) {
+	if _, err := os.Stat(file); err == nil {
+		return
+	}
+
+	util.Fatal(os.MkdirAll(filepath.Dir(file), 0700))
+	fileutils.DownloadFile(url, file)
+}
+
+func extractNatives(jar string, dir string) error {
+	reader, err := zip.OpenReader(jar)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "META-INF") {
+			continue
+		}
+
+		util.Fatal(os.MkdirAll(filepath.Dir(filepath.Join(dir, file.Name)), 0700))
+		in, err1 := file.Open()
+		if err1 != nil {
+			return err1
+		}
+
+		out, err2 := os.Create(filepath.Join(dir, file.Name))
+		if err2 != nil {
+			in.Close()
+			return err2
+		}
+
+		_, err3 := io.Copy(out, in)
+		in.Close()
+		out.Close()
+		if err3 != nil {
+			return err3
+		}
+	}
+	return nil
+}
+
+// downloadLibraries downloads the libraries of a version, extracts legacy natives into nativesDir and returns the classpath
+func downloadLibraries(dotMinecraft string, libraries []launchLibrary, nativesDir string) []string {
+	var classpath []string
+	var seen []string
+	for _, library := range libraries {
+		if !isAllowed(library.Rules) || util.Contains(seen, getLibraryKey(library.Name)) {
+			continue
+		}
+		seen = append(seen, getLibraryKey(library.Name))
+
+		if classifier, ok := library.Natives[getOsName()]; ok {
+			classifier = strings.ReplaceAll(classifier, "${arch}", "64")
+			native := library.Downloads.Classifiers[classifier]
+			file := dotMinecraft + "/libraries/" + native.Path
+
+			downloadMissing(native.Url, file)
+			util.Fatal(extractNatives(file, nativesDir))
+			continue
+		}
+
+		path := library.Downloads.Artifact.Path
+		url := library.Downloads.Artifact.Url
+		if path == "" {
+			path = util.GetMavenPath(library.Name)
+			base := library.Url
+			if base == "" {
+				base = api.MOJANG_LIBRARIES_BASE
+			}
+			url = strings.TrimSuffix(base, "/") + "/" + path
+		}
+
+		if url != "" {
+			downloadMissing(url, dotMinecraft+"/libraries/"+path)
+		}
+		classpath = append(classpath, filepath.Join(dotMinecraft, "libraries", path))
+	}
+	return classpath
+}
+
+// downloadAssets downloads every asset object of an asset index that is not already in .minecraft
+func downloadAssets(dotMinecraft string, id string, url string) {
+	file := dotMinecraft + "/assets/indexes/" + id + ".json"
+	downloadMissing(url, file)
+
+	data, err := ioutil.ReadFile(file)
+	util.Fatal(err)
+
+	var index assetIndex
+	util.Fatal(json.Unmarshal(data, &index))
+
+	hashes := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hash := range hashes {
+				downloadMissing(api.MOJANG_RESOURCES_BASE+"/"+hash[:2]+"/"+hash, dotMinecraft+"/assets/objects/"+hash[:2]+"/"+hash)
+			}
+		}()
+	}
+
+	for _, object := range index.Objects {
+		hashes <- object.Hash
+	}
+	close(hashes)
+	wg.Wait()
+}
+
+// getOfflineUuid returns the uuid the game itself gives an offline player, a version 3 uuid of "OfflinePlayer:<name>"
+func getOfflineUuid(username string) string {
+	hash := md5.Sum([]byte("OfflinePlayer:" + username))
+	hash[6] = hash[6]&0x0f | 0x30
+	hash[8] = hash[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", hash[0:4], hash[4:6], hash[6:8], hash[8:10], hash[10:16])
+}
+
+var launchVariableRegex = regexp.MustCompile(`\$\{(\w+)\}`)
+
+func getArguments(arguments []launchArgument, variables map[string]string) []string {
+	var args []string
+	for _, argument := range arguments {
+		if !isAllowed(argument.Rules) {
+			continue
+		}
+
+		for _, value := range argument.Value {
+			args = append(args, launchVariableRegex.ReplaceAllStringFunc(value, func(variable string) string {
+				if v, ok := variables[variable[2:len(variable)-1]]; ok {
+					return v
+				}
+				return variable
+			}))
+		}
+	}
+	return args
+}
+
+func toArguments(args []string) []launchArgument {
+	var arguments []launchArgument
+	for _, arg := range args {
+		arguments = append(arguments, launchArgument{Value: []string{arg}})
+	}
+	return arguments
+}
+
+// GetLaunchCommand downloads everything an instance needs to start and returns the java command that starts it
+func GetLaunchCommand(instance util.Instance, username string) (c []string, e error) {
+	if instance.Server {
+		return nil, errors.New("server instances are started with their start script")
+	}
+
+	state := fileutils.LoadAppState()
+	id := getVersionId(instance)
+	if _, err := os.Stat(state.DotMinecraft + "/versions/" + id + "/" + id + ".json"); os.IsNotExist(err) {
+		if instance.Loader == "fabric" {
+			api.DownloadFabricJson(&state, instance.Version, instance.LoaderVersion)
+		} else if instance.Loader == "quilt" {
+			api.DownloadQuiltJson(&state, instance.Version, instance.LoaderVersion)
+		}
+	}
+
+	loaderJson, err := readLaunchJson(state.DotMinecraft, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err1 := api.DownloadVersionJson(&state, loaderJson.InheritsFrom); err1 != nil {
+		return nil, err1
+	}
+
+	vanillaJson, err2 := readLaunchJson(state.DotMinecraft, loaderJson.InheritsFrom)
+	if err2 != nil {
+		return nil, err2
+	}
+
+	nativesDir := filepath.Join(state.DotMinecraft, "versions", id, "natives")
+	classpath := downloadLibraries(state.DotMinecraft, append(loaderJson.Libraries, vanillaJson.Libraries...), nativesDir)
+
+	clientJar := filepath.Join(state.DotMinecraft, "versions", vanillaJson.Id, vanillaJson.Id+".jar")
+	downloadMissing(vanillaJson.Downloads.Client.Url, clientJar)
+	classpath = append(classpath, clientJar)
+
+	downloadAssets(state.DotMinecraft, vanillaJson.AssetIndex.Id, vanillaJson.AssetIndex.Url)
+
+	variables := map[string]string{
+		"auth_player_name":    username,
+		"auth_uuid":           strings.ReplaceAll(getOfflineUuid(username), "-", ""),
+		"auth_access_token":   "0",
+		"auth_xuid":           "0",
+		"clientid":            "0",
+		"user_type":           "legacy",
+		"user_properties":     "{}",
+		"version_name":        id,
+		"version_type":        vanillaJson.Type,
+		"game_directory":      GetGameDir(instance),
+		"assets_root":         filepath.Join(state.DotMinecraft, "assets"),
+		"game_assets":         filepath.Join(state.DotMinecraft, "assets"),
+		"assets_index_name":   vanillaJson.AssetIndex.Id,
+		"library_directory":   filepath.Join(state.DotMinecraft, "libraries"),
+		"natives_directory":   nativesDir,
+		"classpath_separator": string(os.PathListSeparator),
+		"classpath":           strings.Join(classpath, string(os.PathListSeparator)),
+		"launcher_name":       "modman",
+		"launcher_version":    util.GetVersion(),
+	}
+
+	gameArgs := vanillaJson.Arguments.Game
+	jvmArgs := vanillaJson.Arguments.Jvm
+	if vanillaJson.MinecraftArguments != "" {
+		gameArgs = toArguments(strings.Fields(vanillaJson.MinecraftArguments))
+		jvmArgs = toArguments([]string{"-Djava.library.path=${natives_directory}", "-cp", "${classpath}"})
+	}
+
+	java := "java"
+	required, err3 := api.GetJavaVersion(instance.Version)
+	if err3 == nil {
+		if install, found := GetMatchingJava(instance, required); found {
+			java = install.Path
+		}
+	}
+	if instance.JavaPath != "" {
+		java = instance.JavaPath
+	}
+
+	command := []string{java}
+	command = append(command, strings.Fields(getJvmArgs(instance))...)
+	if modsFlag := getModsFlag(instance); modsFlag != "" {
+		command = append(command, modsFlag)
+	}
+	command = append(command, getArguments(append(jvmArgs, loaderJson.Arguments.Jvm...), variables)...)
+	command = append(command, loaderJson.MainClass)
+	command = append(command, getArguments(append(gameArgs, loaderJson.Arguments.Game...), variables)...)
+	return command, nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/mrnavastar/modman/api"
+	"github.com/mrnavastar/modman/util"
+	"golang.org/x/mod/semver"
+)
+
+// IsNewerVersion compares loader versions, which are semver without the v prefix golang.org/x/mod/semver expects
+func IsNewerVersion(current string, latest string) bool {
+	return semver.Compare("v"+current, "v"+latest) == -1
+}
+
+// GetLatestLoaderVersion returns the newest stable version of a loader
+func GetLatestLoaderVersion(loader string) (s string, e error) {
+	versions, err := api.GetLoaderVersions(loader)
+	if err != nil {
+		return "", err
+	}
+
+	for _, version := range versions {
+		if version.Stable {
+			return version.Version, nil
+		}
+	}
+	return "", errors.New("failed to find a stable version")
+}
+
+// SetLoaderVersion Must call SaveInstance after using! - switches an instance to another loader version
+func SetLoaderVersion(instance *util.Instance, version string) error {
+	versions, err := api.GetLoaderVersions(instance.Loader)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, v := range versions {
+		if v.Version == version {
+			found = true
+		}
+	}
+
+	if !found {
+		return errors.New("unknown loader version")
+	}
+
+	instance.LoaderVersion = version
+	return installLoader(*instance)
+}
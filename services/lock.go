@@ -0,0 +1,116 @@
+package services
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mrnavastar/modman/util"
+)
+
+// LockedMod is a mod of a lockfile, Sha1 is the hash of the file that was installed when the lockfile was written
+type LockedMod struct {
+	Platform  string
+	ProjectId string
+	Slug      string
+	Name      string
+	Id        string
+	Version   string
+	Filename  string
+	Sha1      string
+	Type      string `json:",omitempty"`
+	World     string `json:",omitempty"`
+}
+
+// Lockfile pins the game version, loader and every installed file of an instance, configs are the sha1 of the tracked config files
+type Lockfile struct {
+	Name          string
+	Version       string
+	Loader        string
+	LoaderVersion string
+	Mods          []LockedMod
+	Configs       map[string]string
+}
+
+// hashModFiles returns the mods of an instance with the sha1 of their installed file as their hash, missing files have none
+func hashModFiles(instance util.Instance) []util.ModData {
+	var mods []util.ModData
+	for _, mod := range instance.Mods {
+		mod.Hash, _ = getFileHash(GetModFile(instance, mod), false)
+		mods = append(mods, mod)
+	}
+	return mods
+}
+
+// WriteLockfile writes what an instance has installed right now into file
+func WriteLockfile(instance util.Instance, file string) error {
+	lock := Lockfile{
+		Name:          instance.Name,
+		Version:       instance.Version,
+		Loader:        instance.Loader,
+		LoaderVersion: instance.LoaderVersion,
+		Mods:          []LockedMod{},
+		Configs:       hashConfigs(instance),
+	}
+
+	for _, mod := range hashModFiles(instance) {
+		lock.Mods = append(lock.Mods, LockedMod{
+			Platform:  mod.Platform,
+			ProjectId: mod.ProjectId,
+			Slug:      mod.Slug,
+			Name:      mod.Name,
+			Id:        mod.Id,
+			Version:   mod.Version,
+			Filename:  mod.Filename,
+			Sha1:      mod.Hash,
+			Type:      mod.Type,
+			World:     mod.World,
+		})
+	}
+
+	data, err := json.MarshalIndent(lock, "", " ")
+	if err != nil {
+		return err
+	}
+
+	if err1 := os.MkdirAll(filepath.Dir(file), 0700); err1 != nil {
+		return err1
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// readLockfile turns a lockfile into an instance whose mods carry the sha1 of their file as hash, and the hashes of its configs
+func readLockfile(file string) (i util.Instance, c map[string]string, e error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return util.Instance{}, nil, err
+	}
+
+	var lock Lockfile
+	if err1 := json.Unmarshal(data, &lock); err1 != nil {
+		return util.Instance{}, nil, err1
+	}
+
+	instance := util.Instance{Name: lock.Name, Version: lock.Version, Loader: lock.Loader, LoaderVersion: lock.LoaderVersion}
+	for _, mod := range lock.Mods {
+		instance.Mods = append(instance.Mods, util.ModData{
+			Platform:  mod.Platform,
+			ProjectId: mod.ProjectId,
+			Slug:      mod.Slug,
+			Name:      mod.Name,
+			Id:        mod.Id,
+			Version:   mod.Version,
+			Filename:  mod.Filename,
+			Hash:      mod.Sha1,
+			Type:      mod.Type,
+			World:     mod.World,
+		})
+	}
+
+	configs := lock.Configs
+	if configs == nil {
+		configs = map[string]string{}
+	}
+	return instance, configs, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mrnavastar/modman/api"
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+	"github.com/pterm/pterm"
+)
+
+// addManualMod remembers a mod that has to be downloaded by hand, replacing an older pending file of the same mod
+func addManualMod(instance *util.Instance, modData util.ModData) {
+	for i, mod := range instance.ManualMods {
+		if mod.ProjectId == modData.ProjectId {
+			instance.ManualMods[i] = modData
+			return
+		}
+	}
+	instance.ManualMods = append(instance.ManualMods, modData)
+}
+
+// FindManualMod finds a mod waiting for a manual download by its name, slug or project id
+func FindManualMod(instance util.Instance, arg string) (m util.ModData, found bool) {
+	for _, mod := range instance.ManualMods {
+		if strings.EqualFold(mod.Name, arg) || strings.EqualFold(mod.Slug, arg) || mod.ProjectId == arg {
+			return mod, true
+		}
+	}
+	return util.ModData{}, false
+}
+
+// ProvideMod Must call SaveInstance after using! - installs a jar the user downloaded for a mod waiting for a manual download,
+// the jar is checked against curseforge by its fingerprint first
+func ProvideMod(instance *util.Instance, pending util.ModData, jar string) error {
+	fingerprint, err := fileutils.GetCurseFingerprint(jar)
+	if err != nil {
+		return err
+	}
+
+	modData, err1 := api.GetCurseModDataByFingerprint(fingerprint)
+	if err1 != nil && err1.Error() != "distribution disabled" {
+		if err1.Error() == "no matching file" {
+			return errors.New("file not recognized")
+		}
+		return err1
+	}
+
+	if modData.ProjectId != pending.ProjectId {
+		return errors.New("file belongs to " + modData.Name)
+	}
+
+	if modData.Id != pending.Id {
+		pterm.Warning.Println(filepath.Base(jar) + " is not the file modman picked for " + pending.Name + ", installing it anyway")
+	}
+
+	//The file may replace an installed version during an update
+	isUpdate := false
+	for _, mod := range append([]util.ModData(nil), instance.Mods...) {
+		if mod.ProjectId == modData.ProjectId {
+			RemoveMod(instance, mod.Id)
+			isUpdate = true
+		}
+	}
+
+	file := GetModFile(*instance, modData)
+	if err2 := os.MkdirAll(filepath.Dir(file), 0700); err2 != nil {
+		return err2
+	}
+	if err2 := fileutils.CopyFile(jar, file); err2 != nil {
+		return err2
+	}
+	installModFile(instance, modData, file, isUpdate)
+
+	for i, mod := range instance.ManualMods {
+		if mod.ProjectId == pending.ProjectId {
+			instance.ManualMods = append(instance.ManualMods[:i], instance.ManualMods[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// GetDownloadsFolder returns the folder browsers save downloads to
+func GetDownloadsFolder() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "Downloads")
+}
+
+// ScanForManualMods Must call SaveInstance after using! - provides every pending mod whose file is found in dir
+func ScanForManualMods(instance *util.Instance, dir string) []util.ModData {
+	var provided []util.ModData
+	for _, mod := range append([]util.ModData(nil), instance.ManualMods...) {
+		jar := filepath.Join(dir, mod.Filename)
+		if _, err := os.Stat(jar); err != nil {
+			continue
+		}
+
+		if err1 := ProvideMod(instance, mod, jar); err1 != nil {
+			pterm.Warning.Println("Found " + jar + " but could not use it: " + err1.Error())
+			continue
+		}
+		provided = append(provided, mod)
+	}
+	return provided
+}
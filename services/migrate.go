@@ -0,0 +1,332 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mrnavastar/modman/api"
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+	"github.com/pterm/pterm"
+)
+
+// replacedMods maps mods that stopped getting releases to the mod that took over their features.
+// When the replacement is already installed the old mod was merged into it and is simply dropped
+var replacedMods = map[string]string{
+	"phosphor": "starlight",
+	"indium":   "sodium",
+}
+
+// loaderLibraries maps the api mods of fabric to their quilt counterparts, they are swapped when an instance changes loader
+var loaderLibraries = map[string]string{
+	"fabric-api":             "qsl",
+	"fabric-language-kotlin": "qkl",
+}
+
+// getLoaderLibrary returns the counterpart of an api mod on the target loader
+func getLoaderLibrary(slug string, loader string) (s string, found bool) {
+	for fabric, quilt := range loaderLibraries {
+		if loader == "quilt" && slug == fabric {
+			return quilt, true
+		}
+		if loader == "fabric" && slug == quilt {
+			return fabric, true
+		}
+	}
+	return "", false
+}
+
+type MigrationResult struct {
+	Mod    util.ModData
+	Target util.ModData
+	Status string
+}
+
+// fetchModData looks up the release of a mod for a loader and game version on the platform it came from
+func fetchModData(mod util.ModData, loader string, version string) (m util.ModData, e error) {
+	if mod.Platform == "curse" {
+		modData, err := api.GetCurseModData(mod.ProjectId, loader, version, mod.Type)
+		modData.World = mod.World
+		return modData, err
+	}
+	if mod.Platform == "url" || mod.Platform == "file" {
+		return fetchJarModData(mod, version)
+	}
+	if mod.Platform == "maven" {
+		if mod.Pinned {
+			return api.GetMavenModData(mod.Id, loader, version)
+		}
+		return api.GetMavenModData(mod.ProjectId, loader, version)
+	}
+	if mod.Platform == "github" {
+		return api.GetGithubModData(mod.ProjectId, version, mod.AssetPattern)
+	}
+	modData, err := api.GetModrinthModData(mod.ProjectId, loader, version, mod.Type)
+	modData.World = mod.World
+	return modData, err
+}
+
+// fetchMigrationTarget is fetchModData that falls back to fabric releases when moving to quilt, which loads most fabric mods.
+// It reports whether the release found only targets fabric
+func fetchMigrationTarget(mod util.ModData, loader string, version string) (m util.ModData, fabricOnly bool, e error) {
+	target, err := fetchModData(mod, loader, version)
+	if err != nil && err.Error() != "distribution disabled" && loader == "quilt" {
+		target, err = fetchModData(mod, "fabric", version)
+		return target, err == nil, err
+	}
+	return target, false, err
+}
+
+func isInstalled(instance util.Instance, slug string) bool {
+	for _, mod := range instance.Mods {
+		if mod.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckMigration finds the release of every mod of an instance for another loader and game version.
+// Status is one of compatible, fabric only, manual, missing, replaced, merged or dependency for required mods the instance does not have yet
+func CheckMigration(instance util.Instance, loader string, version string) []MigrationResult {
+	var results []MigrationResult
+	for _, mod := range instance.Mods {
+		if library, ok := getLoaderLibrary(mod.Slug, loader); ok {
+			target, err := api.GetModrinthModData(library, loader, version, "")
+			if err == nil {
+				results = append(results, MigrationResult{Mod: mod, Target: target, Status: "replaced"})
+				continue
+			}
+		}
+
+		target, fabricOnly, err := fetchMigrationTarget(mod, loader, version)
+		if err != nil && err.Error() == "distribution disabled" {
+			results = append(results, MigrationResult{Mod: mod, Target: target, Status: "manual"})
+			continue
+		}
+
+		if err == nil {
+			status := "compatible"
+			if fabricOnly {
+				status = "fabric only"
+			}
+			results = append(results, MigrationResult{Mod: mod, Target: target, Status: status})
+
+			for _, dep := range target.Dependencies {
+				if !dep.Required || dep.ProjectId == "" || isModDownloaded(&instance, util.ModData{ProjectId: dep.ProjectId}) {
+					continue
+				}
+
+				alreadyListed := false
+				for _, result := range results {
+					if result.Target.ProjectId == dep.ProjectId {
+						alreadyListed = true
+					}
+				}
+
+				if !alreadyListed {
+					depData, err1 := fetchModData(util.ModData{Platform: mod.Platform, ProjectId: dep.ProjectId}, loader, version)
+					if err1 != nil {
+						depData = util.ModData{Name: dep.Name, ProjectId: dep.ProjectId}
+					}
+					results = append(results, MigrationResult{Target: depData, Status: "dependency"})
+				}
+			}
+			continue
+		}
+
+		if replacement, ok := replacedMods[mod.Slug]; ok {
+			if isInstalled(instance, replacement) {
+				results = append(results, MigrationResult{Mod: mod, Status: "merged", Target: util.ModData{Slug: replacement, Name: replacement}})
+				continue
+			}
+
+			target, err1 := api.GetModrinthModData(replacement, loader, version, "")
+			if err1 == nil {
+				results = append(results, MigrationResult{Mod: mod, Target: target, Status: "replaced"})
+				continue
+			}
+		}
+		results = append(results, MigrationResult{Mod: mod, Status: "missing"})
+	}
+	return results
+}
+
+// installMigratedMods adds the releases found by CheckMigration to an instance and reports the mods left behind
+func installMigratedMods(instance *util.Instance, results []MigrationResult, version string) {
+	for _, result := range results {
+		switch result.Status {
+		case "compatible", "fabric only", "dependency", "replaced":
+			if result.Target.Id == "" {
+				pterm.Error.Println("Failed to find " + result.Target.Name + " for " + version)
+				continue
+			}
+
+			if result.Status == "replaced" {
+				pterm.Info.Println(result.Mod.Name + " was replaced by " + result.Target.Name)
+			}
+			if result.Status == "fabric only" {
+				pterm.Warning.Println(result.Mod.Name + " only has a fabric release, it may not work on quilt")
+			}
+
+			err := AddMod(instance, "", result.Target, result.Status == "compatible" || result.Status == "fabric only")
+			if err != nil && err.Error() != "mod already added" {
+				pterm.Error.Println("Failed to install " + result.Target.Name + ": " + err.Error())
+			}
+		case "manual":
+			addManualMod(instance, result.Target)
+		case "merged":
+			pterm.Info.Println(result.Mod.Name + " was merged into " + result.Target.Name)
+		case "missing":
+			pterm.Error.Println(result.Mod.Name + " does not have a version for " + version)
+		}
+	}
+}
+
+// SnapshotInstance clones an instance so it can be restored after an in place migration, snapshots taken within the same second are numbered
+func SnapshotInstance(name string) (s string, e error) {
+	stamp := name + "_Snapshot_" + time.Now().Format("2006-01-02_15-04-05")
+	snapshot := stamp
+	for i := 2; ; i++ {
+		if _, err := GetInstance(snapshot); err != nil {
+			break
+		}
+		snapshot = stamp + "_" + strconv.Itoa(i)
+	}
+	return snapshot, CloneInstance(name, snapshot)
+}
+
+// restoreSnapshot puts the files of a snapshot back into an instance and saves it as it was before, the snapshot is kept
+func restoreSnapshot(instance util.Instance, snapshot string, profile *util.Profile) error {
+	saved, err := GetInstance(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if err1 := os.RemoveAll(instance.Path); err1 != nil {
+		return err1
+	}
+	if err2 := fileutils.CopyDir(saved.Path, instance.Path); err2 != nil {
+		return err2
+	}
+
+	if profile != nil {
+		fileutils.AddProfile(*profile)
+	}
+	return SaveInstance(instance)
+}
+
+// migrateInPlace moves an instance to another loader and game version after saving a snapshot, which is restored when a step fails.
+// Mods without a release for the game version are kept as they are and reported
+func migrateInPlace(instance util.Instance, results []MigrationResult, loader string, version string) (n string, e error) {
+	old := instance
+	instance.Version = version
+	if loader != instance.Loader {
+		instance.Loader = loader
+		if loader == "fabric" {
+			lversion, err := api.GetLatestFabricLoaderVersion()
+			if err != nil {
+				return "", err
+			}
+			instance.LoaderVersion = lversion
+		} else if loader == "quilt" {
+			instance.LoaderVersion = api.GetLatestQuiltLoaderVersion()
+		}
+	}
+
+	snapshot, err1 := SnapshotInstance(old.Name)
+	if err1 != nil {
+		return "", err1
+	}
+	pterm.Info.Println("Saved a snapshot of " + old.Name + " as " + snapshot)
+
+	var profile *util.Profile
+	if p, err2 := fileutils.GetProfile(old.Name); err2 == nil && !old.Server {
+		profile = &p
+	}
+	restore := func(err error) (string, error) {
+		if err3 := restoreSnapshot(old, snapshot, profile); err3 != nil {
+			return "", errors.New(err.Error() + ", restoring " + snapshot + " failed too: " + err3.Error())
+		}
+		return "", errors.New(err.Error() + ", restored " + old.Name + " from " + snapshot)
+	}
+
+	if err2 := installLoader(instance); err2 != nil {
+		return restore(err2)
+	}
+
+	var migrated []MigrationResult
+	for _, result := range results {
+		if result.Status == "missing" {
+			pterm.Warning.Println("Kept " + result.Mod.Name + " " + result.Mod.Version + ", it does not have a version for " + version)
+			continue
+		}
+		if result.Mod.Id != "" {
+			RemoveMod(&instance, result.Mod.Id)
+		}
+		migrated = append(migrated, result)
+	}
+
+	installMigratedMods(&instance, migrated, version)
+	for _, config := range rerenderConfigs(old, instance) {
+		pterm.Warning.Println("Kept your edits to config/" + config + " ~ modman config diff " + config)
+	}
+
+	if err3 := SaveInstance(instance); err3 != nil {
+		return restore(err3)
+	}
+	return instance.Name, nil
+}
+
+// MigrateInstance moves an instance to another loader and game version, either into a new <name>_Migrated instance or in place after a snapshot
+func MigrateInstance(name string, loader string, version string, inPlace bool) (n string, e error) {
+	instance, err := GetInstance(name)
+	if err != nil {
+		return "", err
+	}
+
+	results := CheckMigration(instance, loader, version)
+	if inPlace {
+		return migrateInPlace(instance, results, loader, version)
+	}
+
+	newName := name + "_Migrated"
+	if _, err1 := GetInstance(newName); err1 == nil {
+		newName = name + "_Migrated_" + time.Now().Format("2006-01-02_15-04-05")
+	}
+
+	var err2 error
+	if instance.Server {
+		err2 = CreateServerInstance(newName, loader, version)
+	} else {
+		err2 = CreateInstance(newName, loader, version)
+	}
+	if err2 != nil {
+		return "", err2
+	}
+
+	if instance.Isolated {
+		if err3 := IsolateInstance(newName, false); err3 != nil {
+			return "", err3
+		}
+	}
+
+	newInstance, err3 := GetInstance(newName)
+	if err3 != nil {
+		return "", errors.New("failed to create " + newName)
+	}
+
+	//Tracked configs come along with their local edits
+	newInstance.Configs = instance.Configs
+	newInstance.ConfigVars = instance.ConfigVars
+	if err4 := copyConfigs(instance, newInstance); err4 != nil {
+		return "", err4
+	}
+	rerenderConfigs(instance, newInstance)
+	writeConfigs(newInstance)
+
+	installMigratedMods(&newInstance, results, version)
+	return newName, SaveInstance(newInstance)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+)
+
+// PackTypes are the kinds of packs that can be installed next to mods
+var PackTypes = []string{"resourcepack", "shader", "datapack"}
+
+// GetGameDir returns the folder the game of an instance runs in, resource packs, shaders and saves live there
+func GetGameDir(instance util.Instance) string {
+	if instance.Server || instance.Isolated {
+		return instance.Path
+	}
+	return fileutils.LoadAppState().DotMinecraft
+}
+
+// getServerWorld reads the level-name of a server, which is the folder of its world
+func getServerWorld(instance util.Instance) string {
+	file, err := os.Open(instance.Path + "/server.properties")
+	if err != nil {
+		return "world"
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, "level-name=") && line != "level-name=" {
+			return strings.TrimPrefix(line, "level-name=")
+		}
+	}
+	return "world"
+}
+
+// getWorldFolder returns the folder of a world, servers keep it next to the server jar and clients in saves
+func getWorldFolder(instance util.Instance, world string) string {
+	if instance.Server {
+		return instance.Path + "/" + world
+	}
+	return GetGameDir(instance) + "/saves/" + world
+}
+
+// resolvePackWorld returns the world a datapack goes into, servers default to their own world while clients have to pick a save
+func resolvePackWorld(instance util.Instance, modData util.ModData) (w string, e error) {
+	if modData.Type != "datapack" {
+		return "", nil
+	}
+
+	if modData.World == "" {
+		if instance.Server {
+			return getServerWorld(instance), nil
+		}
+		return "", errors.New("no world chosen")
+	}
+
+	if _, err := os.Stat(getWorldFolder(instance, modData.World)); err != nil && !instance.Server {
+		return "", errors.New("world not found")
+	}
+	return modData.World, nil
+}
+
+// getPackSides returns where a pack is used, resource packs and shaders only do anything on the client
+func getPackSides(packType string) (client string, server string) {
+	if packType == "datapack" {
+		return "required", "required"
+	}
+	return "required", "unsupported"
+}
+
+// GetModFile returns where the file of a mod or pack is installed
+func GetModFile(instance util.Instance, mod util.ModData) string {
+	switch mod.Type {
+	case "resourcepack":
+		return GetGameDir(instance) + "/resourcepacks/" + mod.Filename
+	case "shader":
+		return GetGameDir(instance) + "/shaderpacks/" + mod.Filename
+	case "datapack":
+		return getWorldFolder(instance, mod.World) + "/datapacks/" + mod.Filename
+	}
+	return GetModFolder(instance) + "/" + mod.Filename
+}
+
+// isPackShared checks whether another instance tracks the same pack file, which happens for instances sharing .minecraft
+func isPackShared(instance util.Instance, mod util.ModData, file string) bool {
+	if mod.Type == "" {
+		return false
+	}
+
+	for _, other := range fileutils.LoadAppState().Instances {
+		if other.Name == instance.Name {
+			continue
+		}
+
+		for _, otherMod := range other.Mods {
+			if otherMod.Type != "" && GetModFile(other, otherMod) == file {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/mrnavastar/modman/api"
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+)
+
+const serverProperties = `#Minecraft server properties
+motd=A Minecraft Server
+server-port=25565
+max-players=20
+online-mode=true
+difficulty=easy
+gamemode=survival
+pvp=true
+view-distance=10
+`
+
+const eula = `#By changing the setting below to TRUE you are indicating your agreement to our EULA (https://account.mojang.com/documents/minecraft_eula).
+eula=false
+`
+
+func CreateServerInstance(name string, loader string, version string) error {
+	state := fileutils.LoadAppState()
+
+	for _, instance := range state.Instances {
+		if strings.EqualFold(instance.Name, name) {
+			return errors.New("already instance with that name")
+		}
+	}
+
+	var instance util.Instance
+	instance.Name = name
+	instance.Loader = loader
+	instance.Version = version
+	instance.Path = state.WorkDir + "/instances/" + name
+	instance.Server = true
+
+	if _, err := os.Stat(GetModFolder(instance)); os.IsNotExist(err) {
+		util.Fatal(os.MkdirAll(GetModFolder(instance), 0700))
+	}
+
+	err := api.DownloadServerJar(version, instance.Path+"/server.jar")
+	if err != nil {
+		return err
+	}
+
+	if loader == "fabric" {
+		lversion, err1 := api.GetLatestFabricLoaderVersion()
+		util.Fatal(err1)
+		instance.LoaderVersion = lversion
+
+		util.Fatal(api.InstallFabricServer(version, lversion, instance.Path))
+	} else if loader == "quilt" {
+		lversion := api.GetLatestQuiltLoaderVersion()
+		instance.LoaderVersion = lversion
+
+		util.Fatal(api.InstallQuiltServer(version, lversion, instance.Path))
+	}
+
+	writeStartScripts(instance)
+	writeTemplate(instance.Path+"/eula.txt", eula)
+	writeTemplate(instance.Path+"/server.properties", serverProperties)
+
+	state.Instances = append(state.Instances, instance)
+	fileutils.SaveAppState(state)
+	return nil
+}
+
+// writeTemplate writes content to file, leaving files the user already edited alone
+func writeTemplate(file string, content string) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		util.Fatal(ioutil.WriteFile(file, []byte(content), 0644))
+	}
+}
+
+// getServerJar returns the jar that starts a server instance
+func getServerJar(instance util.Instance) string {
+	if instance.Loader == "fabric" || instance.Loader == "quilt" {
+		return instance.Loader + "-server-launch.jar"
+	}
+	return "server.jar"
+}
+
+// writeStartScripts writes a start.sh and start.bat that launch the server with the java settings of the instance
+func writeStartScripts(instance util.Instance) {
+	java := "java"
+	if instance.JavaPath != "" {
+		java = "\"" + instance.JavaPath + "\""
+	}
+
+	command := java + " " + getJvmArgs(instance)
+	if instance.Loader == "quilt" {
+		command += " -Dloader.gameJarPath=server.jar"
+	}
+	command += " -jar " + getServerJar(instance) + " nogui"
+
+	util.Fatal(ioutil.WriteFile(instance.Path+"/start.sh", []byte("#!/bin/sh\ncd \"$(dirname \"$0\")\"\nexec "+command+"\n"), 0755))
+	util.Fatal(ioutil.WriteFile(instance.Path+"/start.bat", []byte("@echo off\r\ncd /d \"%~dp0\"\r\n"+command+"\r\n"), 0644))
+}
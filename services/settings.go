@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mrnavastar/modman/util"
+)
+
+// GetSettings lists every setting with its value, secrets are masked
+func GetSettings(config util.Config) [][]string {
+	var settings [][]string
+	value := reflect.ValueOf(config)
+	for i := 0; i < value.NumField(); i++ {
+		name := value.Type().Field(i).Name
+		field := fmt.Sprint(value.Field(i).Interface())
+		if value.Field(i).Kind() == reflect.Slice {
+			field = strings.Trim(field, "[]")
+		}
+		if value.Field(i).Kind() == reflect.Ptr {
+			field = ""
+			if !value.Field(i).IsNil() {
+				field = fmt.Sprint(value.Field(i).Elem().Interface())
+			}
+		}
+
+		if (strings.HasSuffix(name, "Key") || strings.HasSuffix(name, "Token")) && field != "" {
+			field = "********"
+		}
+		settings = append(settings, []string{name, field})
+	}
+	return settings
+}
+
+// SetSetting sets a setting by its name, lists are comma separated
+func SetSetting(config *util.Config, key string, value string) error {
+	field := reflect.ValueOf(config).Elem().FieldByNameFunc(func(name string) bool {
+		return strings.EqualFold(name, key)
+	})
+
+	if !field.IsValid() {
+		return errors.New("unknown setting")
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("expected true or false")
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("expected a number")
+		}
+		field.SetInt(int64(i))
+	case reflect.Ptr:
+		//Optional numbers go back to their default when set to nothing
+		if value == "" {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("expected a number")
+		}
+		field.Set(reflect.ValueOf(&i))
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if strings.TrimSpace(item) != "" {
+				list = append(list, strings.TrimSpace(item))
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	}
+	return nil
+}
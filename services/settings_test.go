@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mrnavastar/modman/util"
+)
+
+func getSetting(config util.Config, name string) string {
+	for _, setting := range GetSettings(config) {
+		if setting[0] == name {
+			return setting[1]
+		}
+	}
+	return "missing"
+}
+
+func TestSetSetting(t *testing.T) {
+	var config util.Config
+	if value := getSetting(config, "HttpRetries"); value != "" {
+		t.Errorf("expected HttpRetries to be unset, got %q", value)
+	}
+
+	// 0 turns retries off, which is not the same as leaving them at the default
+	if err := SetSetting(&config, "httpretries", "0"); err != nil || config.HttpRetries == nil || *config.HttpRetries != 0 {
+		t.Errorf("expected HttpRetries to be 0, got %v %v", config.HttpRetries, err)
+	}
+	if value := getSetting(config, "HttpRetries"); value != "0" {
+		t.Errorf("expected HttpRetries to show 0, got %q", value)
+	}
+	if err := SetSetting(&config, "httpretries", ""); err != nil || config.HttpRetries != nil {
+		t.Errorf("expected an empty value to unset HttpRetries, got %v %v", config.HttpRetries, err)
+	}
+	if err := SetSetting(&config, "httpretries", "many"); err == nil {
+		t.Error("expected a number")
+	}
+
+	if err := SetSetting(&config, "mavenrepos", "https://maven.example.com, file:///repo"); err != nil || len(config.MavenRepos) != 2 {
+		t.Errorf("expected 2 repositories, got %v %v", config.MavenRepos, err)
+	}
+	if err := SetSetting(&config, "nothing", "1"); err == nil {
+		t.Error("expected an unknown setting to fail")
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/fileutils"
+)
+
+// builtinDependencies are provided by the game and loaders instead of mods
+var builtinDependencies = []string{"minecraft", "java", "fabricloader", "quilt_loader"}
+
+// getFileHash returns the sha1 of a file, the same hash modrinth lists for its files, or its sha256 when asked for
+func getFileHash(file string, useSha256 bool) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	if useSha256 {
+		return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+	}
+	return fmt.Sprintf("%x", sha1.Sum(data)), nil
+}
+
+// getJarModData builds the mod data of a jar that does not come from a platform out of its fabric.mod.json.
+// The hash is used as the id, so a changed jar counts as a new version
+func getJarModData(file string, platform string, source string) (m util.ModData, modJson fileutils.ModJson, e error) {
+	modJson, err := fileutils.GetModJsonFromJar(file)
+	if err != nil {
+		return util.ModData{}, modJson, err
+	}
+
+	hash, err1 := getFileHash(file, false)
+	if err1 != nil {
+		return util.ModData{}, modJson, err1
+	}
+
+	name := modJson.Name
+	if name == "" {
+		name = modJson.Id
+	}
+
+	modData := util.ModData{
+		Platform:  platform,
+		Slug:      modJson.Id,
+		Name:      strings.Replace(name, " ", "-", -1),
+		ProjectId: modJson.Id,
+		Id:        hash,
+		Version:   modJson.Version,
+		Url:       source,
+		Filename:  filepath.Base(file),
+		Hash:      hash,
+	}
+	modData.ClientSide, modData.ServerSide = getEnvironmentSides(modJson.Environment)
+
+	//Dependencies of jars only have a mod id, they can not be looked up on a platform so they are never installed automatically
+	for dep := range modJson.Depends {
+		if !util.Contains(builtinDependencies, dep) {
+			modData.Dependencies = append(modData.Dependencies, util.Dependency{Name: dep, Required: true})
+		}
+	}
+	return modData, modJson, nil
+}
+
+// getUrlModData downloads a jar from a http(s) link to read its metadata, the download is kept for installing it
+func getUrlModData(link string) (m util.ModData, modJson fileutils.ModJson, e error) {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return util.ModData{}, modJson, errors.New("invalid url")
+	}
+
+	filename := path.Base(parsed.Path)
+	if !strings.HasSuffix(filename, ".jar") {
+		filename = "mod.jar"
+	}
+
+	file, err1 := fileutils.InspectFile(link)
+	if err1 != nil {
+		return util.ModData{}, modJson, err1
+	}
+
+	modData, modJson, err2 := getJarModData(file, "url", link)
+	if err2 != nil {
+		return util.ModData{}, modJson, err2
+	}
+
+	modData.Filename = filename
+	if filename == "mod.jar" {
+		modData.Filename = modData.Slug + "-" + modData.Version + ".jar"
+	}
+	return modData, modJson, nil
+}
+
+// getFileModData reads the metadata of a jar on this computer, it is copied from there when installed
+func getFileModData(file string) (m util.ModData, modJson fileutils.ModJson, e error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return util.ModData{}, modJson, err
+	}
+
+	if _, err1 := os.Stat(abs); err1 != nil {
+		return util.ModData{}, modJson, errors.New("file not found")
+	}
+	return getJarModData(abs, "file", abs)
+}
+
+// fetchJarModData checks a url or file mod for a new version. Files are read again while links are only downloaded
+// again when the mod asked for it with CheckUrl, otherwise the mod is kept as it is
+func fetchJarModData(mod util.ModData, version string) (m util.ModData, e error) {
+	var modData util.ModData
+	var modJson fileutils.ModJson
+	var err error
+	switch {
+	case mod.Platform == "url" && mod.CheckUrl:
+		modData, modJson, err = getUrlModData(mod.Url)
+		modData.CheckUrl = true
+	case mod.Platform == "file":
+		if _, err1 := os.Stat(mod.Url); err1 != nil {
+			return mod, nil
+		}
+		modData, modJson, err = getFileModData(mod.Url)
+	default:
+		return mod, nil
+	}
+
+	if err != nil {
+		return util.ModData{}, err
+	}
+
+	if !util.MatchesVersionPredicate(modJson.Depends["minecraft"], version) {
+		return util.ModData{}, errors.New("failed to find matching version")
+	}
+	return modData, nil
+}
+
+// downloadModFile puts the jar of a mod into the mod folder, copying it for file mods, and checks it against the recorded hash
+func downloadModFile(modData util.ModData, file string) error {
+	if modData.Platform == "file" {
+		if err := fileutils.CopyFile(modData.Url, file); err != nil {
+			return err
+		}
+	} else {
+		fileutils.DownloadFile(modData.Url, file)
+	}
+
+	if modData.Hash == "" {
+		return nil
+	}
+
+	//A sha256 is twice as long as a sha1
+	hash, err1 := getFileHash(file, len(modData.Hash) == 64)
+	if err1 != nil {
+		return err1
+	}
+
+	if hash != modData.Hash {
+		os.Remove(file)
+		return errors.New("hash mismatch")
+	}
+	return nil
+}
@@ -1,6 +1,11 @@
 package util
 
-import "github.com/pterm/pterm"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
 
 func GetVersion() string {
 	return "1.0"
@@ -15,6 +20,155 @@ func Contains(list []string, str string) bool {
 	return false
 }
 
+// GetMavenPath turns maven coordinates (group:artifact:version[:classifier]) into a repository relative jar path
+func GetMavenPath(name string) string {
+	parts := strings.Split(name, ":")
+	if len(parts) < 3 {
+		return ""
+	}
+
+	file := parts[1] + "-" + parts[2]
+	if len(parts) > 3 {
+		file += "-" + parts[3]
+	}
+	return strings.ReplaceAll(parts[0], ".", "/") + "/" + parts[1] + "/" + parts[2] + "/" + file + ".jar"
+}
+
+func splitPreRelease(version string) (release string, pre string) {
+	parts := strings.SplitN(version, "-", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// CompareVersions compares dotted versions part by part as numbers, a pre-release (1.20-pre1) comes before its release.
+// It returns -1, 0 or 1 when a is older, the same or newer than b
+func CompareVersions(a string, b string) int {
+	aRelease, aPre := splitPreRelease(a)
+	bRelease, bPre := splitPreRelease(b)
+
+	aParts := strings.Split(aRelease, ".")
+	bParts := strings.Split(bRelease, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart string
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+
+		aNumber, err := strconv.Atoi(aPart)
+		bNumber, err1 := strconv.Atoi(bPart)
+		if err != nil || err1 != nil {
+			if aPart != bPart {
+				return strings.Compare(aPart, bPart)
+			}
+			continue
+		}
+
+		if aNumber != bNumber {
+			if aNumber < bNumber {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// MatchesVersionPredicate checks a version against a fabric.mod.json version predicate like ">=1.19.2 <1.20", "~1.19" or "1.19.x".
+// A list of predicates matches when any of them does
+func MatchesVersionPredicate(predicate interface{}, version string) bool {
+	switch p := predicate.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		for _, item := range p {
+			if MatchesVersionPredicate(item, version) {
+				return true
+			}
+		}
+		return false
+	case string:
+		for _, constraint := range strings.Fields(p) {
+			if !matchesConstraint(constraint, version) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func matchesConstraint(constraint string, version string) bool {
+	if constraint == "*" {
+		return true
+	}
+
+	for _, operator := range []string{">=", "<=", ">", "<", "=", "~", "^"} {
+		if !strings.HasPrefix(constraint, operator) {
+			continue
+		}
+
+		target := strings.TrimPrefix(constraint, operator)
+		compared := CompareVersions(version, target)
+		switch operator {
+		case ">=":
+			return compared >= 0
+		case "<=":
+			return compared <= 0
+		case ">":
+			return compared > 0
+		case "<":
+			return compared < 0
+		case "=":
+			return compared == 0
+		case "~":
+			parts := strings.Split(target, ".")
+			if len(parts) > 2 {
+				parts = parts[:2]
+			}
+			return compared >= 0 && hasPrefixParts(version, parts)
+		case "^":
+			return compared >= 0 && hasPrefixParts(version, strings.Split(target, ".")[:1])
+		}
+	}
+
+	// 1.19.x and 1.19.* match every 1.19 release
+	parts := strings.Split(constraint, ".")
+	if last := parts[len(parts)-1]; last == "x" || last == "X" || last == "*" {
+		return hasPrefixParts(version, parts[:len(parts)-1])
+	}
+	return CompareVersions(version, constraint) == 0
+}
+
+// hasPrefixParts checks that a version starts with the given dot separated parts
+func hasPrefixParts(version string, parts []string) bool {
+	release, _ := splitPreRelease(version)
+	versionParts := strings.Split(release, ".")
+	if len(versionParts) < len(parts) {
+		return false
+	}
+
+	for i, part := range parts {
+		if versionParts[i] != part {
+			return false
+		}
+	}
+	return true
+}
+
 func Fatal(err error) {
 	if err != nil {
 		pterm.Fatal.Println(err)
@@ -8,11 +8,14 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/buger/jsonparser"
 	"github.com/mrnavastar/modman/util"
+	"github.com/mrnavastar/modman/util/netutils"
 	"github.com/zalando/go-keyring"
 )
 
@@ -48,22 +51,207 @@ func (wc WriteCounter) PrintProgress() {
 }
 
 
+// inspected keeps the files downloaded to look inside them by their url, installing one of them copies it instead of downloading it again
+var inspected = map[string]string{}
+var inspectedDir string
+var inspectedMutex sync.Mutex
+
 func DownloadFile(url string, filepath string) {
-	resp, err := http.Get(url)
-	util.Fatal(err)
+	util.Fatal(FetchFile(url, filepath))
+}
+
+// FetchFile downloads a file like DownloadFile, returning errors instead of exiting
+func FetchFile(url string, file string) error {
+	inspectedMutex.Lock()
+	inspectedFile, found := inspected[url]
+	inspectedMutex.Unlock()
+	if found {
+		return CopyFile(inspectedFile, file)
+	}
+
+	resp, err := netutils.Client.Get(url)
+	if err != nil {
+		return err
+	}
 	defer resp.Body.Close()
 
-	total, err := strconv.Atoi(resp.Header.Get("Content-Length"))
-	util.Fatal(err)
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("failed to download " + url + ": " + resp.Status)
+	}
 
-	file, err := os.Create(filepath)
-	util.Fatal(err)
-	defer file.Close()
+	total, _ := strconv.Atoi(resp.Header.Get("Content-Length"))
+
+	out, err1 := os.Create(file)
+	if err1 != nil {
+		return err1
+	}
+	defer out.Close()
 
 	counter := &WriteCounter{}
 	counter.Total = int64(total)
-	_, err = io.Copy(file, io.TeeReader(resp.Body, counter))
-	util.Fatal(err)
+	if _, err2 := io.Copy(out, io.TeeReader(resp.Body, counter)); err2 != nil {
+		os.Remove(file)
+		return err2
+	}
+	return nil
+}
+
+// InspectFile downloads a file to look inside it before it is installed, returning where it was saved. Installing it afterwards
+// with DownloadFile or FetchFile copies this download instead of fetching it again
+func InspectFile(url string) (f string, e error) {
+	inspectedMutex.Lock()
+	file, found := inspected[url]
+	if !found && inspectedDir == "" {
+		dir, err := ioutil.TempDir("", "modman")
+		if err != nil {
+			inspectedMutex.Unlock()
+			return "", err
+		}
+		inspectedDir = dir
+	}
+	dir := inspectedDir
+	inspectedMutex.Unlock()
+	if found {
+		return file, nil
+	}
+
+	out, err1 := ioutil.TempFile(dir, "inspected")
+	if err1 != nil {
+		return "", err1
+	}
+	out.Close()
+
+	if err2 := FetchFile(url, out.Name()); err2 != nil {
+		os.Remove(out.Name())
+		return "", err2
+	}
+
+	inspectedMutex.Lock()
+	inspected[url] = out.Name()
+	inspectedMutex.Unlock()
+	return out.Name(), nil
+}
+
+// RemoveInspectedFiles deletes the files downloaded by InspectFile, main calls it once a command is done
+func RemoveInspectedFiles() {
+	inspectedMutex.Lock()
+	defer inspectedMutex.Unlock()
+	if inspectedDir != "" {
+		os.RemoveAll(inspectedDir)
+	}
+	inspected = map[string]string{}
+	inspectedDir = ""
+}
+
+func CopyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err1 := os.Create(dst)
+	if err1 != nil {
+		return err1
+	}
+	defer out.Close()
+
+	_, err2 := io.Copy(out, in)
+	return err2
+}
+
+// CopyDir copies the contents of the src folder into dst
+func CopyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err1 := filepath.Rel(src, path)
+		if err1 != nil {
+			return err1
+		}
+
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, relative), 0700)
+		}
+		return CopyFile(path, filepath.Join(dst, relative))
+	})
+}
+
+// WriteLauncherJar writes a jar that only holds a manifest, so java -jar can start mainClass with classpath
+func WriteLauncherJar(file string, mainClass string, classpath []string) error {
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := zip.NewWriter(out)
+	manifest, err1 := writer.Create("META-INF/MANIFEST.MF")
+	if err1 != nil {
+		return err1
+	}
+
+	content := "Manifest-Version: 1.0\r\n" + wrapManifestLine("Main-Class: "+mainClass) + wrapManifestLine("Class-Path: "+strings.Join(classpath, " "))
+	if _, err2 := manifest.Write([]byte(content)); err2 != nil {
+		return err2
+	}
+	return writer.Close()
+}
+
+// wrapManifestLine splits a manifest attribute into the 72 byte lines the jar spec allows
+func wrapManifestLine(line string) string {
+	wrapped := ""
+	for len(line) > 72 {
+		wrapped += line[:72] + "\r\n"
+		line = " " + line[72:]
+	}
+	return wrapped + line + "\r\n"
+}
+
+// GetCurseFingerprint computes the murmur2 hash curseforge identifies files by, whitespace bytes are left out
+func GetCurseFingerprint(file string) (uint32, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	var normalized []byte
+	for _, b := range data {
+		if b != 9 && b != 10 && b != 13 && b != 32 {
+			normalized = append(normalized, b)
+		}
+	}
+
+	const m = 0x5bd1e995
+	h := 1 ^ uint32(len(normalized))
+	i := 0
+	for ; i+4 <= len(normalized); i += 4 {
+		k := uint32(normalized[i]) | uint32(normalized[i+1])<<8 | uint32(normalized[i+2])<<16 | uint32(normalized[i+3])<<24
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	switch len(normalized) - i {
+	case 3:
+		h ^= uint32(normalized[i+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(normalized[i+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(normalized[i])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h, nil
 }
 
 type ModJson struct {
@@ -71,29 +259,35 @@ type ModJson struct {
 	Version string
 	Name string
 	Description string
-	Depends struct {
-		FabricLoader string
-		Fabric string
-	}
+	Environment string
+	Depends map[string]interface{}
 }
 
+// GetModJsonFromJar reads the fabric.mod.json of a jar, files that are not a jar or have none return an error
 func GetModJsonFromJar(filepath string) (modJson ModJson, err error) {
 	reader, err := zip.OpenReader(filepath)
-	util.Fatal(err)
+	if err != nil {
+		return ModJson{}, errors.New("not a fabric mod")
+	}
 	defer reader.Close()
 
 	for _, file := range reader.File {
 		if file.Name == "fabric.mod.json" {
 			f, err := file.Open()
-			util.Fatal(err)
+			if err != nil {
+				return ModJson{}, err
+			}
 			defer f.Close()
 
 			content, err1 := ioutil.ReadAll(f)
-			util.Fatal(err1)
+			if err1 != nil {
+				return ModJson{}, err1
+			}
 
 			var modJson ModJson
-			err2 := json.Unmarshal([]byte(strings.Replace(string(content), "\n", "", -1)), &modJson)
-			util.Fatal(err2)
+			if err2 := json.Unmarshal([]byte(strings.Replace(string(content), "\n", "", -1)), &modJson); err2 != nil {
+				return ModJson{}, errors.New("not a fabric mod")
+			}
 			return modJson, nil
 		}
 	}
@@ -114,6 +308,41 @@ func AddProfile(profile util.Profile) {
 	util.Fatal(ioutil.WriteFile(state.DotMinecraft + "/launcher_profiles.json", newProfiles, 0644))
 }
 
+func GetProfile(name string) (p util.Profile, e error) {
+	state := LoadAppState()
+	profiles, err1 := ioutil.ReadFile(state.DotMinecraft + "/launcher_profiles.json")
+	util.Fatal(err1)
+
+	data, _, _, err2 := jsonparser.Get(profiles, "profiles", name)
+	if err2 != nil {
+		return util.Profile{}, errors.New("failed to find profile")
+	}
+
+	var profile util.Profile
+	util.Fatal(json.Unmarshal(data, &profile))
+	return profile, nil
+}
+
+// RenameProfile replaces the profile stored under oldName with profile in a single write
+func RenameProfile(oldName string, profile util.Profile) error {
+	state := LoadAppState()
+	profiles, err1 := ioutil.ReadFile(state.DotMinecraft + "/launcher_profiles.json")
+	if err1 != nil {
+		return err1
+	}
+
+	data, err2 := json.MarshalIndent(profile, "", " ")
+	if err2 != nil {
+		return err2
+	}
+
+	newProfiles, err3 := jsonparser.Set(jsonparser.Delete(profiles, "profiles", oldName), data, "profiles", profile.Name)
+	if err3 != nil {
+		return err3
+	}
+	return ioutil.WriteFile(state.DotMinecraft+"/launcher_profiles.json", newProfiles, 0644)
+}
+
 func RemoveProfile(name string) {
 	state := LoadAppState()
 	profiles, err1 := ioutil.ReadFile(state.DotMinecraft + "/launcher_profiles.json")
@@ -12,18 +12,27 @@ type State struct {
 	DotMinecraft   string
 	WorkDir        string
 	ActiveInstance string
+	Config         util.Config
 	Instances      []util.Instance
 }
 
 func SaveAppState(state State) {
+	util.Fatal(WriteAppState(state))
+}
+
+// WriteAppState is SaveAppState for callers that need to recover from a failed write
+func WriteAppState(state State) error {
 	dotMinecraft, err := keyring.Get("modman", "dot_minecraft")
-	util.Fatal(err)
+	if err != nil {
+		return err
+	}
 
 	file, err1 := json.MarshalIndent(state, "", " ")
-	util.Fatal(err1)
+	if err1 != nil {
+		return err1
+	}
 
-	err2 := ioutil.WriteFile(dotMinecraft+"/modman/modman.json", file, 0644)
-	util.Fatal(err2)
+	return ioutil.WriteFile(dotMinecraft+"/modman/modman.json", file, 0644)
 }
 
 func LoadAppState() State {
@@ -41,3 +50,31 @@ func LoadAppState() State {
 	state.WorkDir = dotMinecraft + "/modman"
 	return state
 }
+
+// GetWorkDir returns the modman folder inside .minecraft, it fails when modman has not been set up yet
+func GetWorkDir() (string, error) {
+	dotMinecraft, err := keyring.Get("modman", "dot_minecraft")
+	if err != nil {
+		return "", err
+	}
+	return dotMinecraft + "/modman", nil
+}
+
+// LoadConfig returns the user settings, or the defaults when modman has not been set up yet
+func LoadConfig() util.Config {
+	dotMinecraft, err := keyring.Get("modman", "dot_minecraft")
+	if err != nil {
+		return util.Config{}
+	}
+
+	data, err1 := ioutil.ReadFile(dotMinecraft + "/modman/modman.json")
+	if err1 != nil {
+		return util.Config{}
+	}
+
+	var state State
+	if err2 := json.Unmarshal(data, &state); err2 != nil {
+		return util.Config{}
+	}
+	return state.Config
+}
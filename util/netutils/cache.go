@@ -0,0 +1,258 @@
+package netutils
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cacheEntry is what is kept next to a cached response body
+type cacheEntry struct {
+	Url     string
+	Status  int
+	Header  http.Header
+	Fetched time.Time
+}
+
+// cacheTtl is how long a response from a host, optionally under a path, is used without asking the server again
+type cacheTtl struct {
+	Host string
+	Path string
+	Ttl  time.Duration
+}
+
+// forever marks responses that never change, like files addressed by their hash or id
+const forever = time.Duration(1<<63 - 1)
+
+// cacheTtls is checked in order, responses from hosts not listed are always revalidated but still serve offline use
+var cacheTtls = []cacheTtl{
+	{Host: "launchermeta.mojang.com", Path: "/mc/game/version_manifest", Ttl: time.Hour},
+	{Host: "piston-meta.mojang.com", Path: "/mc/game/version_manifest", Ttl: time.Hour},
+	{Host: "launchermeta.mojang.com", Ttl: forever},
+	{Host: "piston-meta.mojang.com", Ttl: forever},
+	{Host: "piston-data.mojang.com", Ttl: forever},
+	{Host: "meta.fabricmc.net", Ttl: time.Hour},
+	{Host: "meta.quiltmc.org", Ttl: time.Hour},
+	{Host: "api.modrinth.com", Ttl: 10 * time.Minute},
+	{Host: "api.curseforge.com", Ttl: 10 * time.Minute},
+	{Host: "cdn.modrinth.com", Ttl: forever},
+	{Host: "edge.forgecdn.net", Ttl: forever},
+	{Host: "mediafilez.forgecdn.net", Ttl: forever},
+	{Host: "maven.fabricmc.net", Ttl: forever},
+	{Host: "maven.quiltmc.org", Ttl: forever},
+}
+
+// uncachedBases serve files that already end up in .minecraft and are only downloaded when missing there, api sets them
+// to the configured mojang endpoints
+var uncachedBases = []string{"https://libraries.minecraft.net/", "https://resources.download.minecraft.net/"}
+
+// cache answers GET requests from disk when it can and stores what the next transport fetches
+type cache struct {
+	next    http.RoundTripper
+	dir     string
+	offline bool
+}
+
+var responseCache = &cache{next: shared}
+
+// SetCache stores responses under dir, offline serves every request from there and fails for anything that is not cached
+func SetCache(dir string, offline bool) {
+	responseCache.dir = dir
+	responseCache.offline = offline
+}
+
+// IsOffline reports whether modman was told not to touch the network
+func IsOffline() bool {
+	return responseCache.offline
+}
+
+// SetUncachedBases sets the urls whose files are never cached, like the libraries and assets kept in .minecraft
+func SetUncachedBases(bases ...string) {
+	uncachedBases = nil
+	for _, base := range bases {
+		uncachedBases = append(uncachedBases, strings.TrimSuffix(base, "/")+"/")
+	}
+}
+
+func getTtl(request *http.Request) time.Duration {
+	for _, ttl := range cacheTtls {
+		if request.URL.Host == ttl.Host && strings.HasPrefix(request.URL.Path, ttl.Path) {
+			return ttl.Ttl
+		}
+	}
+	return 0
+}
+
+func isCacheable(request *http.Request) bool {
+	for _, base := range uncachedBases {
+		if strings.HasPrefix(request.URL.String(), base) {
+			return false
+		}
+	}
+	return request.Method == http.MethodGet && (request.URL.Scheme == "http" || request.URL.Scheme == "https")
+}
+
+// getAuthorization returns the credentials a request is sent with, set on the request itself or for its host by SetHostHeader
+func getAuthorization(request *http.Request) string {
+	if authorization := request.Header.Get("Authorization"); authorization != "" {
+		return authorization
+	}
+	return shared.headers[request.URL.Host]["Authorization"]
+}
+
+// getPaths returns the files of the cache entry for a request. Responses fetched with credentials, like private github repos,
+// are kept apart by a hash of them so requests without the same credentials never see them. The curseforge api key is left
+// out since it does not change the response
+func (c *cache) getPaths(request *http.Request) (meta string, body string) {
+	name := request.URL.String()
+	if authorization := getAuthorization(request); authorization != "" {
+		name += " " + fmt.Sprintf("%x", sha1.Sum([]byte(authorization)))
+	}
+
+	key := fmt.Sprintf("%x", sha1.Sum([]byte(name)))
+	return filepath.Join(c.dir, key[:2], key+".json"), filepath.Join(c.dir, key[:2], key)
+}
+
+func (c *cache) load(request *http.Request) (entry cacheEntry, ok bool) {
+	meta, body := c.getPaths(request)
+	data, err := ioutil.ReadFile(meta)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+
+	if err1 := json.Unmarshal(data, &entry); err1 != nil || entry.Url != request.URL.String() {
+		return cacheEntry{}, false
+	}
+
+	if _, err2 := os.Stat(body); err2 != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *cache) saveEntry(request *http.Request, entry cacheEntry) error {
+	meta, _ := c.getPaths(request)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(meta, data, 0644)
+}
+
+// store writes a response to the cache and returns it again, reading its body from the cached file
+func (c *cache) store(request *http.Request, resp *http.Response) (*http.Response, error) {
+	meta, body := c.getPaths(request)
+	if err := os.MkdirAll(filepath.Dir(meta), 0700); err != nil {
+		return resp, nil
+	}
+
+	file, err1 := os.Create(body + ".part")
+	if err1 != nil {
+		return resp, nil
+	}
+
+	_, err2 := io.Copy(file, resp.Body)
+	resp.Body.Close()
+	file.Close()
+	if err2 != nil {
+		os.Remove(body + ".part")
+		return nil, err2
+	}
+
+	if err3 := os.Rename(body+".part", body); err3 != nil {
+		return nil, err3
+	}
+
+	entry := cacheEntry{Url: request.URL.String(), Status: resp.StatusCode, Header: resp.Header, Fetched: time.Now()}
+	if err4 := c.saveEntry(request, entry); err4 != nil {
+		return nil, err4
+	}
+	return c.respond(request, entry)
+}
+
+// respond builds a response from a cache entry
+func (c *cache) respond(request *http.Request, entry cacheEntry) (*http.Response, error) {
+	_, body := c.getPaths(request)
+	file, err := os.Open(body)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err1 := file.Stat()
+	if err1 != nil {
+		file.Close()
+		return nil, err1
+	}
+
+	header := entry.Header.Clone()
+	header.Del("Content-Encoding")
+	header.Set("Content-Length", fmt.Sprint(info.Size()))
+	return &http.Response{
+		Status:        fmt.Sprint(entry.Status) + " " + http.StatusText(entry.Status),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          file,
+		ContentLength: info.Size(),
+		Request:       request,
+	}, nil
+}
+
+func (c *cache) RoundTrip(request *http.Request) (*http.Response, error) {
+	if c.dir == "" || !isCacheable(request) {
+		if c.offline && request.URL.Scheme != "file" {
+			return nil, errors.New("offline: can not " + request.Method + " " + request.URL.String())
+		}
+		return c.next.RoundTrip(request)
+	}
+
+	entry, cached := c.load(request)
+	if c.offline {
+		if !cached {
+			return nil, errors.New("offline: " + request.URL.String() + " is not cached, run the command once while online")
+		}
+		return c.respond(request, entry)
+	}
+
+	if cached && time.Since(entry.Fetched) < getTtl(request) {
+		return c.respond(request, entry)
+	}
+
+	if cached {
+		request = request.Clone(request.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			request.Header.Set("If-None-Match", etag)
+		}
+		if modified := entry.Header.Get("Last-Modified"); modified != "" {
+			request.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := c.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached && resp.StatusCode == http.StatusNotModified {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		entry.Fetched = time.Now()
+		c.saveEntry(request, entry)
+		return c.respond(request, entry)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	return c.store(request, resp)
+}
@@ -0,0 +1,127 @@
+package netutils
+
+import (
+	"net/http"
+	"testing"
+)
+
+// roundTripFunc answers requests with a function, counting them
+type roundTripFunc struct {
+	requests int
+	answer   func(request *http.Request) *http.Response
+}
+
+func (f *roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	f.requests++
+	return f.answer(request), nil
+}
+
+func TestCache(t *testing.T) {
+	next := &roundTripFunc{answer: func(request *http.Request) *http.Response {
+		if request.Header.Get("If-None-Match") == `"v1"` {
+			return newResponse(request, http.StatusNotModified, nil, nil)
+		}
+		return newResponse(request, http.StatusOK, http.Header{"Etag": {`"v1"`}}, []byte("manifest"))
+	}}
+	c := &cache{next: next, dir: t.TempDir()}
+
+	// the manifest is kept for an hour, hosts without a ttl are revalidated with the etag
+	for i := 0; i < 2; i++ {
+		resp, err := c.RoundTrip(newRequest(t, http.MethodGet, "https://launchermeta.mojang.com/mc/game/version_manifest_v2.json", ""))
+		if err != nil || readBody(t, resp) != "manifest" {
+			t.Fatalf("expected the manifest, got %v", err)
+		}
+	}
+	if next.requests != 1 {
+		t.Errorf("expected the second request to be answered from the cache, got %d requests", next.requests)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.RoundTrip(newRequest(t, http.MethodGet, "https://example.com/mods.json", ""))
+		if err != nil || resp.StatusCode != http.StatusOK || readBody(t, resp) != "manifest" {
+			t.Fatalf("expected the cached body after a 304, got %v", err)
+		}
+	}
+	if next.requests != 3 {
+		t.Errorf("expected the second request to be revalidated, got %d requests", next.requests)
+	}
+
+	// offline only answers what is cached
+	c.offline = true
+	if resp, err := c.RoundTrip(newRequest(t, http.MethodGet, "https://example.com/mods.json", "")); err != nil || readBody(t, resp) != "manifest" {
+		t.Errorf("expected the cached response offline, got %v", err)
+	}
+	if _, err := c.RoundTrip(newRequest(t, http.MethodGet, "https://example.com/other.json", "")); err == nil {
+		t.Error("expected a request that is not cached to fail offline")
+	}
+	if next.requests != 3 {
+		t.Errorf("expected offline requests to stay off the network, got %d requests", next.requests)
+	}
+}
+
+func TestCacheAuthorization(t *testing.T) {
+	next := &roundTripFunc{answer: func(request *http.Request) *http.Response {
+		return newResponse(request, http.StatusOK, nil, []byte("private release"))
+	}}
+	c := &cache{next: next, dir: t.TempDir()}
+	release := "https://api.github.com/repos/example/private/releases"
+
+	SetHostHeader("api.github.com", "Authorization", "Bearer secret")
+	t.Cleanup(func() {
+		delete(shared.headers, "api.github.com")
+	})
+	if resp, err := c.RoundTrip(newRequest(t, http.MethodGet, release, "")); err != nil || readBody(t, resp) != "private release" {
+		t.Fatalf("expected the private release, got %v", err)
+	}
+
+	// without the token, or with another one, the cached response is not used
+	delete(shared.headers, "api.github.com")
+	c.offline = true
+	if _, err := c.RoundTrip(newRequest(t, http.MethodGet, release, "")); err == nil {
+		t.Error("expected a request without the token to miss the cache")
+	}
+	other := newRequest(t, http.MethodGet, release, "")
+	other.Header.Set("Authorization", "Bearer other")
+	if _, err := c.RoundTrip(other); err == nil {
+		t.Error("expected a request with another token to miss the cache")
+	}
+
+	SetHostHeader("api.github.com", "Authorization", "Bearer secret")
+	if resp, err := c.RoundTrip(newRequest(t, http.MethodGet, release, "")); err != nil || readBody(t, resp) != "private release" {
+		t.Errorf("expected the same token to get the cached response offline, got %v", err)
+	}
+}
+
+func TestUncachedBases(t *testing.T) {
+	next := &roundTripFunc{answer: func(request *http.Request) *http.Response {
+		return newResponse(request, http.StatusOK, nil, []byte("library"))
+	}}
+	c := &cache{next: next, dir: t.TempDir()}
+
+	SetUncachedBases("https://mirror.example.com/libraries/", "https://mirror.example.com/resources")
+	t.Cleanup(func() {
+		SetUncachedBases("https://libraries.minecraft.net", "https://resources.download.minecraft.net")
+	})
+
+	for _, u := range []string{"https://mirror.example.com/libraries/a.jar", "https://mirror.example.com/libraries/a.jar", "https://mirror.example.com/resources/ab/abcd"} {
+		resp, err := c.RoundTrip(newRequest(t, http.MethodGet, u, ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if next.requests != 3 {
+		t.Errorf("expected files from the mirror to never be cached, got %d requests", next.requests)
+	}
+
+	// the real hosts are cached like any other once a mirror replaces them
+	resp, err := c.RoundTrip(newRequest(t, http.MethodGet, "https://libraries.minecraft.net/a.jar", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	c.offline = true
+	if resp1, err1 := c.RoundTrip(newRequest(t, http.MethodGet, "https://libraries.minecraft.net/a.jar", "")); err1 != nil || readBody(t, resp1) != "library" {
+		t.Errorf("expected the old host to be cached, got %v", err1)
+	}
+}
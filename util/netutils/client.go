@@ -0,0 +1,273 @@
+package netutils
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mrnavastar/modman/util"
+)
+
+const defaultTimeout = 30
+const defaultRetries = 3
+const maxBackoff = time.Minute
+
+// hostLimit is what we know about the rate limit of a host
+type hostLimit struct {
+	mutex     sync.Mutex
+	next      time.Time
+	remaining int
+	reset     time.Time
+}
+
+// urlHeader holds the headers for the urls matching a pattern
+type urlHeader struct {
+	pattern *regexp.Regexp
+	headers map[string]string
+}
+
+// transport retries failed requests, keeps to the rate limits of every host and identifies modman to them
+type transport struct {
+	base     *http.Transport
+	fixtures *fixtures
+	retries  int
+	// timeout is how long to wait for a server, a Retry-After longer than it is cut short
+	timeout time.Duration
+	// headers are added to every request to a host, like the tokens of private apis
+	headers map[string]map[string]string
+	// urlHeaders are added to the requests whose url matches their pattern, keyed by the pattern
+	urlHeaders map[string]*urlHeader
+	// interval is the smallest gap between two requests to the same host, zero leaves it to the host's headers
+	interval time.Duration
+	hosts    sync.Map
+}
+
+var shared = &transport{
+	base:       newBaseTransport(&http.Transport{Proxy: http.ProxyFromEnvironment}),
+	retries:    defaultRetries,
+	timeout:    defaultTimeout * time.Second,
+	headers:    map[string]map[string]string{},
+	urlHeaders: map[string]*urlHeader{},
+}
+
+// Client is the http client every request of modman goes through, answered from the response cache when possible
+var Client = &http.Client{Transport: responseCache}
+
+// Configure applies the network settings of the config to the shared client
+func Configure(config util.Config) {
+	timeout := time.Duration(config.HttpTimeout) * time.Second
+	if config.HttpTimeout <= 0 {
+		timeout = defaultTimeout * time.Second
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.HttpProxy != "" {
+		if proxyUrl, err := url.Parse(config.HttpProxy); err == nil {
+			proxy = http.ProxyURL(proxyUrl)
+		}
+	}
+
+	shared.base = newBaseTransport(&http.Transport{
+		Proxy:                 proxy,
+		DialContext:           (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   8,
+	})
+
+	shared.timeout = timeout
+	shared.retries = defaultRetries
+	if config.HttpRetries != nil && *config.HttpRetries >= 0 {
+		shared.retries = *config.HttpRetries
+	}
+
+	shared.interval = 0
+	if config.HttpRateLimit > 0 {
+		shared.interval = time.Second / time.Duration(config.HttpRateLimit)
+	}
+}
+
+// newBaseTransport lets file:// urls be fetched like any other, so local repositories work the same as remote ones
+func newBaseTransport(base *http.Transport) *http.Transport {
+	base.RegisterProtocol("file", fileTransport{})
+	return base
+}
+
+// SetHostHeader sends a header with every request to a host, redirects to other hosts do not get it
+func SetHostHeader(host string, key string, value string) {
+	if shared.headers[host] == nil {
+		shared.headers[host] = map[string]string{}
+	}
+	shared.headers[host][key] = value
+}
+
+// SetUrlHeader sends a header with every request whose url matches a pattern, like the accept header github asset downloads need
+func SetUrlHeader(pattern string, key string, value string) {
+	if shared.urlHeaders[pattern] == nil {
+		shared.urlHeaders[pattern] = &urlHeader{pattern: regexp.MustCompile(pattern), headers: map[string]string{}}
+	}
+	shared.urlHeaders[pattern].headers[key] = value
+}
+
+// GetUserAgent returns the user agent modman sends, modrinth asks for one that names the project
+func GetUserAgent() string {
+	return "mrnavastar/modman/" + util.GetVersion() + " (github.com/mrnavastar/modman)"
+}
+
+func (t *transport) getHost(host string) *hostLimit {
+	limit, _ := t.hosts.LoadOrStore(host, &hostLimit{remaining: -1})
+	return limit.(*hostLimit)
+}
+
+// wait blocks until a request to the host is allowed
+func (t *transport) wait(limit *hostLimit) {
+	limit.mutex.Lock()
+	now := time.Now()
+	start := now
+	if limit.next.After(start) {
+		start = limit.next
+	}
+	if limit.remaining == 0 && limit.reset.After(start) {
+		start = limit.reset
+		limit.remaining = -1
+	}
+	limit.next = start.Add(t.interval)
+	limit.mutex.Unlock()
+
+	time.Sleep(start.Sub(now))
+}
+
+// update reads the X-Ratelimit headers of a response, reset is the number of seconds until the limit resets
+func (limit *hostLimit) update(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-Ratelimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	limit.mutex.Lock()
+	defer limit.mutex.Unlock()
+	limit.remaining = remaining
+	if reset, err1 := strconv.Atoi(header.Get("X-Ratelimit-Reset")); err1 == nil {
+		limit.reset = time.Now().Add(time.Duration(reset) * time.Second)
+	}
+}
+
+// getRetryAfter reads the Retry-After header, which is either a number of seconds or a date. Waits longer than limit are cut to it
+func getRetryAfter(header http.Header, limit time.Duration) (d time.Duration, ok bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err1 := http.ParseTime(value); err1 == nil {
+		wait = time.Until(date)
+	} else {
+		return 0, false
+	}
+
+	if wait > limit {
+		return limit, true
+	}
+	return wait, true
+}
+
+func getBackoff(attempt int) time.Duration {
+	backoff := time.Duration(float64(500*time.Millisecond) * math.Pow(2, float64(attempt)))
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+func shouldRetry(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// isLocal checks for requests that never leave this computer, like local files and stand-ins for a service on localhost
+func isLocal(u *url.URL) bool {
+	if u.Scheme == "file" || u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+// send hands a request to the network, or to the fixtures when they are set. Local requests are never recorded or replayed
+func (t *transport) send(base http.RoundTripper, request *http.Request) (*http.Response, error) {
+	if t.fixtures == nil || isLocal(request.URL) {
+		return base.RoundTrip(request)
+	}
+
+	if !t.fixtures.record {
+		return t.fixtures.replay(request)
+	}
+
+	resp, err := base.RoundTrip(request)
+	if err != nil || resp.StatusCode == http.StatusNotModified {
+		return resp, err
+	}
+	return t.fixtures.save(request, resp)
+}
+
+func (t *transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	limit := t.getHost(request.URL.Host)
+	base := t.base
+
+	for attempt := 0; ; attempt++ {
+		attemptRequest := request.Clone(request.Context())
+		attemptRequest.Header.Set("User-Agent", GetUserAgent())
+		for key, value := range t.headers[request.URL.Host] {
+			attemptRequest.Header.Set(key, value)
+		}
+		for _, header := range t.urlHeaders {
+			if header.pattern.MatchString(request.URL.String()) {
+				for key, value := range header.headers {
+					attemptRequest.Header.Set(key, value)
+				}
+			}
+		}
+		if attempt > 0 && request.Body != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptRequest.Body = body
+		}
+
+		t.wait(limit)
+		resp, err := t.send(base, attemptRequest)
+
+		//Local files either exist or not, there is nothing to wait for
+		canRetry := attempt < t.retries && (request.Body == nil || request.GetBody != nil) && request.URL.Scheme != "file"
+		if err != nil {
+			if !canRetry || request.Context().Err() != nil {
+				return nil, err
+			}
+			time.Sleep(getBackoff(attempt))
+			continue
+		}
+
+		if t.fixtures == nil || t.fixtures.record {
+			limit.update(resp.Header)
+		}
+		if !canRetry || !shouldRetry(resp.StatusCode) {
+			return resp, nil
+		}
+
+		delay := getBackoff(attempt)
+		if retryAfter, ok := getRetryAfter(resp.Header, t.timeout); ok && retryAfter > delay {
+			delay = retryAfter
+		}
+		resp.Body.Close()
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,57 @@
+package netutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mrnavastar/modman/util"
+)
+
+func TestGetRetryAfter(t *testing.T) {
+	for value, expected := range map[string]time.Duration{"5": 5 * time.Second, "3600": 30 * time.Second, "0": 0} {
+		if wait, ok := getRetryAfter(http.Header{"Retry-After": {value}}, 30*time.Second); !ok || wait != expected {
+			t.Errorf("expected %v for %s, got %v %v", expected, value, wait, ok)
+		}
+	}
+
+	date := time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat)
+	if wait, ok := getRetryAfter(http.Header{"Retry-After": {date}}, 30*time.Second); !ok || wait != 30*time.Second {
+		t.Errorf("expected a date a day away to be cut to the limit, got %v %v", wait, ok)
+	}
+	if _, ok := getRetryAfter(http.Header{}, 30*time.Second); ok {
+		t.Error("expected no wait without the header")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer Configure(util.Config{})
+
+	for retries, expected := range map[int]int32{0: 1, 1: 2} {
+		retries := retries
+		Configure(util.Config{HttpRetries: &retries, HttpTimeout: 1})
+		atomic.StoreInt32(&requests, 0)
+
+		start := time.Now()
+		resp, err := Client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if requests != expected || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("expected %d requests with %d retries, got %d", expected, retries, requests)
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Errorf("expected the hour long Retry-After to be cut to the timeout, took %v", time.Since(start))
+		}
+	}
+}
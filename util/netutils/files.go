@@ -0,0 +1,64 @@
+package netutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileRoots are the folders file:// urls may read from, anything else like a download url from an api is refused
+var fileRoots []string
+
+// fileTransport answers file:// urls from the folders in fileRoots
+type fileTransport struct{}
+
+// SetFileRoots sets the folders file:// urls may read from, like local maven repositories
+func SetFileRoots(roots []string) {
+	fileRoots = nil
+	for _, root := range roots {
+		if abs, err := filepath.Abs(root); err == nil {
+			fileRoots = append(fileRoots, abs)
+		}
+	}
+}
+
+// GetFilePath turns a file:// url into a path. Windows drive letters come as file:///C:/repo or file://C:/repo
+func GetFilePath(u *url.URL) string {
+	path := u.Path
+	if u.Host != "" && u.Host != "localhost" {
+		path = u.Host + path
+	}
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+func isInFileRoots(file string) bool {
+	for _, root := range fileRoots {
+		if file == root || strings.HasPrefix(file, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (fileTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	file := GetFilePath(request.URL)
+	if !isInFileRoots(file) {
+		return nil, errors.New(request.URL.String() + " is not in a local repository")
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return newResponse(request, http.StatusNotFound, nil, nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(request, http.StatusOK, nil, data), nil
+}
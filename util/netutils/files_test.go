@@ -0,0 +1,60 @@
+package netutils
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetFilePath(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"file:///home/steve/repo/a.jar":          "/home/steve/repo/a.jar",
+		"file://localhost/home/steve/repo/a.jar": "/home/steve/repo/a.jar",
+		"file:///C:/repo/a.jar":                  "C:/repo/a.jar",
+		"file://C:/repo/a.jar":                   "C:/repo/a.jar",
+		"file:///home/steve/repo/../a.jar":       "/home/steve/a.jar",
+	} {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path := GetFilePath(u); path != filepath.FromSlash(expected) {
+			t.Errorf("expected %s for %s, got %s", filepath.FromSlash(expected), raw, path)
+		}
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+	repo := t.TempDir()
+	SetFileRoots([]string{repo})
+	t.Cleanup(func() {
+		SetFileRoots(nil)
+	})
+	if err := ioutil.WriteFile(filepath.Join(repo, "a.jar"), []byte("jar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: newBaseTransport(&http.Transport{})}
+	resp, err := client.Get("file://" + filepath.ToSlash(filepath.Join(repo, "a.jar")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err1 := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err1 != nil || resp.StatusCode != http.StatusOK || string(data) != "jar" {
+		t.Errorf("expected the file from the repository, got %d %q %v", resp.StatusCode, data, err1)
+	}
+
+	if missing, err2 := client.Get("file://" + filepath.ToSlash(filepath.Join(repo, "b.jar"))); err2 != nil || missing.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing file, got %v %v", missing, err2)
+	}
+
+	// files outside the repositories, even through .., are refused
+	for _, outside := range []string{filepath.Join(t.TempDir(), "a.jar"), repo + "/../a.jar"} {
+		if _, err3 := client.Get("file://" + filepath.ToSlash(outside)); err3 == nil {
+			t.Errorf("expected %s to be refused", outside)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package netutils
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// fixture is a recorded response, its body is kept in a file of the same name ending in .body
+type fixture struct {
+	Method   string
+	Url      string
+	BodyHash string `json:",omitempty"`
+	Status   int
+	Header   http.Header
+}
+
+// fixtures records every response from the network into a folder, or replays them from it without touching the network
+type fixtures struct {
+	dir    string
+	record bool
+	mutex  sync.Mutex
+	loaded map[string]string
+}
+
+var unsafeNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SetFixtures makes every request go to the fixtures in dir, when record is set the real responses are written there instead
+func SetFixtures(dir string, record bool) {
+	shared.fixtures = &fixtures{dir: dir, record: record}
+}
+
+// getBodyHash identifies the body of a request, requests without one have an empty hash
+func getBodyHash(request *http.Request) (string, error) {
+	if request.Body == nil || request.GetBody == nil {
+		return "", nil
+	}
+
+	body, err := request.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	data, err1 := ioutil.ReadAll(body)
+	if err1 != nil || len(data) == 0 {
+		return "", err1
+	}
+	return fmt.Sprintf("%x", sha1.Sum(data)), nil
+}
+
+func getFixtureKey(method string, url string, bodyHash string) string {
+	return method + " " + url + " " + bodyHash
+}
+
+// getName returns a readable file name for a fixture, the hash keeps requests that only differ in their query or body apart
+func getName(request *http.Request, bodyHash string) string {
+	name := unsafeNameRegex.ReplaceAllString(request.URL.Host+request.URL.Path, "_")
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	hash := fmt.Sprintf("%x", sha1.Sum([]byte(getFixtureKey(request.Method, request.URL.String(), bodyHash))))
+	return name + "-" + hash[:8]
+}
+
+// load indexes the fixtures in the folder by their request, the file names do not matter so fixtures can be written by hand
+func (f *fixtures) load() error {
+	if f.loaded != nil {
+		return nil
+	}
+
+	f.loaded = map[string]string{}
+	files, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err1 := ioutil.ReadFile(file)
+		if err1 != nil {
+			return err1
+		}
+
+		var recorded fixture
+		if err2 := json.Unmarshal(data, &recorded); err2 != nil {
+			return errors.New("invalid fixture " + file + ": " + err2.Error())
+		}
+		f.loaded[getFixtureKey(recorded.Method, recorded.Url, recorded.BodyHash)] = strings.TrimSuffix(file, ".json")
+	}
+	return nil
+}
+
+func (f *fixtures) replay(request *http.Request) (*http.Response, error) {
+	bodyHash, err := getBodyHash(request)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mutex.Lock()
+	err1 := f.load()
+	file, found := f.loaded[getFixtureKey(request.Method, request.URL.String(), bodyHash)]
+	f.mutex.Unlock()
+	if err1 != nil {
+		return nil, err1
+	}
+	if !found {
+		return nil, errors.New("no fixture for " + request.Method + " " + request.URL.String())
+	}
+
+	data, err2 := ioutil.ReadFile(file + ".json")
+	if err2 != nil {
+		return nil, err2
+	}
+
+	var recorded fixture
+	if err3 := json.Unmarshal(data, &recorded); err3 != nil {
+		return nil, err3
+	}
+
+	body, err4 := ioutil.ReadFile(file + ".body")
+	if err4 != nil {
+		return nil, err4
+	}
+	return newResponse(request, recorded.Status, recorded.Header, body), nil
+}
+
+func (f *fixtures) save(request *http.Request, resp *http.Response) (*http.Response, error) {
+	bodyHash, err := getBodyHash(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err1 := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err1 != nil {
+		return nil, err1
+	}
+
+	header := resp.Header.Clone()
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	header.Del("Set-Cookie")
+
+	recorded := fixture{Method: request.Method, Url: request.URL.String(), BodyHash: bodyHash, Status: resp.StatusCode, Header: header}
+	data, err2 := json.MarshalIndent(recorded, "", " ")
+	if err2 != nil {
+		return nil, err2
+	}
+
+	if err3 := os.MkdirAll(f.dir, 0700); err3 != nil {
+		return nil, err3
+	}
+
+	file := filepath.Join(f.dir, getName(request, bodyHash))
+	if err4 := ioutil.WriteFile(file+".json", data, 0644); err4 != nil {
+		return nil, err4
+	}
+	if err5 := ioutil.WriteFile(file+".body", body, 0644); err5 != nil {
+		return nil, err5
+	}
+	return newResponse(request, resp.StatusCode, header, body), nil
+}
+
+func newResponse(request *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Length", fmt.Sprint(len(body)))
+
+	return &http.Response{
+		Status:        fmt.Sprint(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}
+}
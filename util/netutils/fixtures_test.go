@@ -0,0 +1,67 @@
+package netutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func newRequest(t *testing.T, method string, url string, body string) *http.Request {
+	t.Helper()
+	var request *http.Request
+	var err error
+	if body == "" {
+		request, err = http.NewRequest(method, url, nil)
+	} else {
+		request, err = http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFixturesRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	recorder := &fixtures{dir: dir, record: true}
+
+	// requests to the same url are told apart by their body, like the hashes posted to modrinth
+	for _, body := range []string{`{"hashes": ["a"]}`, `{"hashes": ["b"]}`} {
+		header := http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"session=1"}}
+		resp := newResponse(nil, http.StatusOK, header, []byte("response to "+body))
+		if _, err := recorder.save(newRequest(t, http.MethodPost, "https://api.modrinth.com/v2/version_files/update", body), resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := recorder.save(newRequest(t, http.MethodGet, "https://api.modrinth.com/v2/project/sodium", ""), newResponse(nil, http.StatusNotFound, nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	player := &fixtures{dir: dir}
+	resp, err := player.replay(newRequest(t, http.MethodPost, "https://api.modrinth.com/v2/version_files/update", `{"hashes": ["b"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); body != `response to {"hashes": ["b"]}` || resp.Header.Get("Set-Cookie") != "" {
+		t.Errorf("expected the recorded response without cookies, got %q %v", body, resp.Header)
+	}
+
+	if notFound, err1 := player.replay(newRequest(t, http.MethodGet, "https://api.modrinth.com/v2/project/sodium", "")); err1 != nil || notFound.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the recorded 404, got %v %v", notFound, err1)
+	}
+
+	if _, err2 := player.replay(newRequest(t, http.MethodGet, "https://api.modrinth.com/v2/project/iris", "")); err2 == nil {
+		t.Error("expected requests that were not recorded to fail")
+	}
+}
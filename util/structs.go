@@ -16,7 +16,30 @@ type ModData struct {
 	Version      string
 	Url          string
 	Filename     string
+	ClientSide   string
+	ServerSide   string
 	Dependencies []Dependency
+	// Type is resourcepack, shader or datapack for packs and empty for mods
+	Type string `json:",omitempty"`
+	// World is the save a datapack is installed into
+	World string `json:",omitempty"`
+	// AssetPattern picks the release asset of github mods by file name, ex: *-fabric-*.jar
+	AssetPattern string `json:",omitempty"`
+	// Hash is the sha1 or sha256 of the jar, checked after every download
+	Hash string `json:",omitempty"`
+	// Pinned mods are left alone by update
+	Pinned bool `json:",omitempty"`
+	// CheckUrl makes update download url mods again to look for a changed jar
+	CheckUrl bool `json:",omitempty"`
+}
+
+// ConfigFile is a file of the config folder that an instance tracks, its default content travels with exports
+type ConfigFile struct {
+	// Path is relative to the config folder, ex: sodium-options.json
+	Path    string
+	Content string
+	// Template configs are rendered with text/template before they are written, ex: {{.Name}} or {{.Vars.server}}
+	Template bool `json:",omitempty"`
 }
 
 type Instance struct {
@@ -24,16 +47,55 @@ type Instance struct {
 	Path          string
 	Version       string
 	Mods          []ModData
+	ManualMods    []ModData
 	Loader        string
 	LoaderVersion string
+	LoaderPinned  bool
+	Server        bool
+	Memory        string
+	JvmArgs       string
+	JavaPath      string
+	// Isolated instances run in their own folder with their own mods, configs and saves instead of the shared .minecraft
+	Isolated bool `json:",omitempty"`
+	// Configs are the files of the config folder the instance tracks
+	Configs []ConfigFile `json:",omitempty"`
+	// ConfigVars are the values config templates can use as {{.Vars.name}}
+	ConfigVars map[string]string `json:",omitempty"`
 }
 
 type Profile struct {
-	Name          string
-	Type          string
-	Icon          string
-	LastVersionId string
-	Created       string
-	JavaArgs      string
-	LastUsed      string
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Icon          string `json:"icon"`
+	LastVersionId string `json:"lastVersionId"`
+	Created       string `json:"created"`
+	JavaArgs      string `json:"javaArgs"`
+	JavaDir       string `json:"javaDir,omitempty"`
+	GameDir       string `json:"gameDir,omitempty"`
+	LastUsed      string `json:"lastUsed"`
+}
+
+type Config struct {
+	CurseApiKey string
+	// GithubToken is sent to github so mods can come from private repos
+	GithubToken string
+	// MavenRepos are searched in order for maven: mods, local repositories work with file:// urls
+	MavenRepos []string
+	// HttpTimeout is how many seconds to wait for a server to connect and answer, 0 uses the default of 30
+	HttpTimeout int
+	// HttpRetries is how often a failed request is retried, unset uses the default of 3 and 0 turns retries off
+	HttpRetries *int `json:",omitempty"`
+	// HttpRateLimit caps the requests per second sent to a single host, 0 only keeps to the limits hosts report
+	HttpRateLimit int
+	// HttpProxy is the url of a proxy for every request, the HTTPS_PROXY environment variable is used when empty
+	HttpProxy string
+	// The Base and Url settings point modman at mirrors or local stand-ins of the upstream services, empty uses the real ones
+	ModrinthApiBase     string
+	CurseApiBase        string
+	FabricMetaBase      string
+	QuiltMetaBase       string
+	MojangManifestUrl   string
+	MojangLibrariesBase string
+	MojangResourcesBase string
+	GithubApiBase       string
 }